@@ -7,3 +7,21 @@ const ContextKeyProps contextKey = "props"
 
 // ContextKeyViewData key.
 const ContextKeyViewData contextKey = "viewData"
+
+// ContextKeyErrors key.
+const ContextKeyErrors contextKey = "errors"
+
+// ContextKeyEncryptHistory key.
+const ContextKeyEncryptHistory contextKey = "encryptHistory"
+
+// ContextKeyHistory key.
+const ContextKeyHistory contextKey = "history"
+
+// ContextKeySession key.
+const ContextKeySession contextKey = "session"
+
+// contextKeyRelease key.
+const contextKeyRelease contextKey = "release"
+
+// contextKeyErrorPage key.
+const contextKeyErrorPage contextKey = "errorPage"
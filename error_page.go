@@ -0,0 +1,150 @@
+package inertia
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrorPage is the component, and the props along with the status prop, rendered
+// for an error status. See ErrorPages.
+type ErrorPage struct {
+	Component string
+	Props     Props
+}
+
+// errorPageState tells the error page interception apart responses rendered by Inertia,
+// and keeps what the panic page shows of a panic raised while resolving props.
+type errorPageState struct {
+	rendered bool
+
+	mu    sync.Mutex
+	props Props
+	stack []byte
+}
+
+// RenderError renders the error page of the status code, passed to the component
+// as the status prop. Inertia requests get a page object the client swaps in like
+// any other visit, full page requests the root template.
+func (i *Inertia) RenderError(w http.ResponseWriter, r *http.Request, status int) error {
+	component, props := i.errorPage(status)
+
+	return i.render(w, r, component, props, status)
+}
+
+// HandleError responds to an error returned by Render or a handler with ErrorHandler
+// when set, otherwise with the error page and a 500 status. A plain text error
+// is sent when the error page cannot be rendered either, e.g. a shared prop fails.
+// With Debug the plain text error holds the error message instead.
+func (i *Inertia) HandleError(w http.ResponseWriter, r *http.Request, err error) {
+	// The client went away, there is nobody to respond to.
+	if errors.Is(err, context.Canceled) && r.Context().Err() != nil {
+		return
+	}
+
+	if i.ErrorHandler != nil {
+		i.ErrorHandler(w, r, err)
+
+		return
+	}
+
+	i.renderErrorPage(w, r, http.StatusInternalServerError, err.Error())
+}
+
+// errorPage returns the component and props rendered for the status code.
+func (i *Inertia) errorPage(status int) (string, Props) {
+	page := i.ErrorPages[status]
+
+	component := page.Component
+	if component == "" {
+		component = i.ErrorComponent
+	}
+
+	return component, mergeProps(Props{"status": status}, page.Props)
+}
+
+// renderErrorPage renders the error page of the status code, falling back to plain text.
+// With Debug server errors are sent as plain text with their detail, which the Inertia
+// client shows in its error modal.
+func (i *Inertia) renderErrorPage(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	if i.Debug && status >= http.StatusInternalServerError {
+		http.Error(w, detail, status)
+
+		return
+	}
+
+	if err := i.RenderError(w, r, status); err != nil {
+		http.Error(w, http.StatusText(status), status)
+	}
+}
+
+// interceptsStatus reports whether handler responses with the status code are replaced by the error page.
+func (i *Inertia) interceptsStatus(status int) bool {
+	if !i.InterceptErrors || i.Debug && status >= http.StatusInternalServerError {
+		return false
+	}
+
+	_, ok := i.ErrorPages[status]
+
+	return ok
+}
+
+// errorPageStateFrom returns the error page state of the request, nil outside withErrorPages.
+func errorPageStateFrom(r *http.Request) *errorPageState {
+	state, _ := r.Context().Value(contextKeyErrorPage).(*errorPageState)
+
+	return state
+}
+
+// markRendered flags the response as rendered by Inertia, so it is not intercepted.
+func markRendered(r *http.Request) {
+	if state := errorPageStateFrom(r); state != nil {
+		state.rendered = true
+	}
+}
+
+// withErrorPages replaces the responses of next whose status has an ErrorPages entry,
+// unless rendered by Inertia, when InterceptErrors is set, and recovers panics.
+func (i *Inertia) withErrorPages(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := &errorPageState{}
+		r = r.WithContext(context.WithValue(r.Context(), contextKeyErrorPage, state))
+
+		// status is the intercepted status code, 0 when the response is written through.
+		var status int
+
+		ew := &responseWriter{
+			ResponseWriter: w,
+			onWriteHeader: func(code int) int {
+				if state.rendered || !i.interceptsStatus(code) {
+					return code
+				}
+
+				status = code
+
+				return 0
+			},
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				// The response is already on its way, or the handler asked to abort it.
+				if p == http.ErrAbortHandler || ew.written() {
+					panic(p)
+				}
+
+				i.recoverPanic(w, r, p, state)
+
+				return
+			}
+
+			if status != 0 {
+				w.Header().Del("Content-Length")
+				i.renderErrorPage(w, r, status, "")
+			}
+		}()
+
+		next.ServeHTTP(ew, r)
+	})
+}
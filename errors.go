@@ -9,6 +9,36 @@ var (
 	// ErrInvalidContextViewData error.
 	ErrInvalidContextViewData = errors.New("inertia: could not convert context view data to map")
 
+	// ErrInvalidContextErrors error.
+	ErrInvalidContextErrors = errors.New("inertia: could not convert context errors to map")
+
+	// ErrInvalidSession error.
+	ErrInvalidSession = errors.New("inertia: invalid session cookie")
+
+	// ErrSessionTooLarge error.
+	ErrSessionTooLarge = errors.New("inertia: session cookie exceeds 4096 bytes")
+
+	// ErrNoSessionKeys error.
+	ErrNoSessionKeys = errors.New("inertia: cookie session store has no secret keys")
+
+	// ErrEmptySessionSecret error.
+	ErrEmptySessionSecret = errors.New("inertia: empty cookie session secret")
+
+	// ErrNoSession error.
+	ErrNoSession = errors.New("inertia: no session, set a SessionStore and use the Middleware")
+
+	// ErrViteEntryNotFound error.
+	ErrViteEntryNotFound = errors.New("inertia: entry not found in vite manifest")
+
+	// ErrNoBuildRevision error.
+	ErrNoBuildRevision = errors.New("inertia: no vcs revision in build info")
+
+	// ErrPropConflict error.
+	ErrPropConflict = errors.New("inertia: prop defined by several sources")
+
+	// ErrInvalidPropPrecedence error.
+	ErrInvalidPropPrecedence = errors.New("inertia: prop precedence must list every prop source exactly once")
+
 	// ErrBadSsrStatusCode error.
 	ErrBadSsrStatusCode = errors.New("inertia: bad ssr status code >= 400")
 
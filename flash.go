@@ -0,0 +1,92 @@
+package inertia
+
+import "net/http"
+
+// Session keys holding the data flashed to the next request.
+const (
+	sessionKeyFlash  = "_flash"
+	sessionKeyErrors = "_errors"
+)
+
+// Flash stores a value that is shared under the FlashKey prop on the next rendered page,
+// typically the page the current response redirects to. It requires a SessionStore.
+func (i *Inertia) Flash(w http.ResponseWriter, r *http.Request, key string, value any) error {
+	session := SessionFromContext(r.Context())
+	if session == nil {
+		return ErrNoSession
+	}
+
+	flash := toProps(session.Get(sessionKeyFlash))
+	flash[key] = value
+	session.Put(sessionKeyFlash, flash)
+
+	return nil
+}
+
+// FlashErrors stores validation errors that are shared as the `errors` prop on the next rendered page.
+// It requires a SessionStore.
+func (i *Inertia) FlashErrors(w http.ResponseWriter, r *http.Request, errors ValidationErrors) error {
+	session := SessionFromContext(r.Context())
+	if session == nil {
+		return ErrNoSession
+	}
+
+	flashed := toValidationErrors(session.Get(sessionKeyErrors))
+	for key, val := range errors {
+		flashed[key] = val
+	}
+	session.Put(sessionKeyErrors, flashed)
+
+	return nil
+}
+
+// pullFlash returns and clears the flash data of the request session.
+func pullFlash(r *http.Request) Props {
+	if session := SessionFromContext(r.Context()); session != nil {
+		return toProps(session.Pull(sessionKeyFlash))
+	}
+
+	return Props{}
+}
+
+// pullFlashErrors returns and clears the validation errors flashed to the request session.
+func pullFlashErrors(r *http.Request) ValidationErrors {
+	if session := SessionFromContext(r.Context()); session != nil {
+		return toValidationErrors(session.Pull(sessionKeyErrors))
+	}
+
+	return ValidationErrors{}
+}
+
+// toProps converts flashed data, which is a generic map after a session round trip.
+func toProps(val any) Props {
+	props := Props{}
+
+	if val, ok := val.(map[string]any); ok {
+		for key, value := range val {
+			props[key] = value
+		}
+	}
+
+	return props
+}
+
+// toValidationErrors converts flashed validation errors, which are a generic map after a session round trip.
+func toValidationErrors(val any) ValidationErrors {
+	errors := ValidationErrors{}
+
+	switch val := val.(type) {
+	case ValidationErrors:
+		for key, message := range val {
+			errors[key] = message
+		}
+	case map[string]any:
+		for key, message := range val {
+			if message, ok := message.(string); ok {
+				errors[key] = message
+			}
+		}
+	}
+
+	return errors
+}
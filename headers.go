@@ -8,6 +8,7 @@ type HeaderTypes struct {
 	PartialComponent string
 	PartialOnly      string
 	PartialExcept    string
+	Reset            string
 }
 
 var Headers = HeaderTypes{
@@ -18,4 +19,5 @@ var Headers = HeaderTypes{
 	PartialComponent: "X-Inertia-Partial-Component",
 	PartialOnly:      "X-Inertia-Partial-Data",
 	PartialExcept:    "X-Inertia-Partial-Except",
+	Reset:            "X-Inertia-Reset",
 }
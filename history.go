@@ -0,0 +1,103 @@
+package inertia
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// clearHistoryCookie carries the clear history flag across a redirect.
+const clearHistoryCookie = "inertia_clear_history"
+
+type historyState struct {
+	// header is the response header ClearHistory sets the cookie on.
+	header http.Header
+	// cookie reports whether the request carries the clear history cookie.
+	cookie bool
+	clear  bool
+}
+
+// WithEncryptHistory overrides the instance EncryptHistory setting for the passed context.Context.
+//
+// https://inertiajs.com/history-encryption
+func (i *Inertia) WithEncryptHistory(ctx context.Context, encrypt bool) context.Context {
+	return context.WithValue(ctx, ContextKeyEncryptHistory, encrypt)
+}
+
+// EncryptHistoryMiddleware enables history encryption for every page rendered by next.
+func (i *Inertia) EncryptHistoryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(i.WithEncryptHistory(r.Context(), true)))
+	})
+}
+
+// ClearHistory clears the client's encrypted history state on the next rendered page,
+// which may be the page the current response redirects to. It requires the Middleware.
+func (i *Inertia) ClearHistory(ctx context.Context) {
+	state, ok := ctx.Value(ContextKeyHistory).(*historyState)
+	if !ok || state.clear {
+		return
+	}
+
+	state.clear = true
+
+	// Carries the flag over to the next rendered page, unless this response renders it.
+	cookie := &http.Cookie{Name: clearHistoryCookie, Value: "1", Path: "/", HttpOnly: true}
+	state.header.Add("Set-Cookie", cookie.String())
+}
+
+func (i *Inertia) shouldEncryptHistory(r *http.Request) bool {
+	if encrypt, ok := r.Context().Value(ContextKeyEncryptHistory).(bool); ok {
+		return encrypt
+	}
+
+	return i.EncryptHistory
+}
+
+// consumeClearHistory reports whether the rendered page clears the history. The flag
+// is consumed: the cookie carrying it is dropped from the response and the client.
+func (i *Inertia) consumeClearHistory(w http.ResponseWriter, r *http.Request) bool {
+	state, ok := r.Context().Value(ContextKeyHistory).(*historyState)
+	if !ok || !state.clear {
+		return false
+	}
+
+	dropCookie(w.Header(), clearHistoryCookie)
+
+	if state.cookie {
+		http.SetCookie(w, &http.Cookie{Name: clearHistoryCookie, Path: "/", MaxAge: -1})
+	}
+
+	state.clear, state.cookie = false, false
+
+	return true
+}
+
+// withHistory restores the clear history flag set before a redirect. The flag is
+// left untouched until a page is rendered, so requests for assets or data keep it.
+func (i *Inertia) withHistory(w http.ResponseWriter, r *http.Request) *http.Request {
+	state := &historyState{header: w.Header()}
+
+	if _, err := r.Cookie(clearHistoryCookie); err == nil {
+		state.cookie, state.clear = true, true
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), ContextKeyHistory, state))
+}
+
+// dropCookie removes the cookie from the Set-Cookie headers not sent yet.
+func dropCookie(header http.Header, name string) {
+	cookies := header["Set-Cookie"][:0]
+
+	for _, cookie := range header["Set-Cookie"] {
+		if !strings.HasPrefix(cookie, name+"=") {
+			cookies = append(cookies, cookie)
+		}
+	}
+
+	if len(cookies) == 0 {
+		header.Del("Set-Cookie")
+	} else {
+		header["Set-Cookie"] = cookies
+	}
+}
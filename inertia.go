@@ -7,31 +7,102 @@ import (
 	"html/template"
 	"io/fs"
 	"net/http"
-	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Inertia type.
 type Inertia struct {
-	Url           string
-	rootTemplate  string
-	version       string
+	Url string
+
+	// SharedProps are merged into the props of every page. SharedFuncMap holds the
+	// functions shared with the root template. Both may be set directly while
+	// configuring the instance; once requests are served, use Share, ShareAlways
+	// and ShareFunc, which are safe to call concurrently with Render.
 	SharedProps   Props
 	SharedFuncMap template.FuncMap
-	templateFS    fs.FS
-	SsrURL        string
-	SsrClient     *http.Client
+	sharedMu      sync.RWMutex
+
+	templateFS fs.FS
+	SsrURL     string
+	SsrClient  *http.Client
+
+	// EncryptHistory encrypts the page state stored in the browser history.
+	EncryptHistory bool
+
+	// SessionStore persists session values between requests. The Middleware attaches
+	// a per-request Session to the request context when it is set. OnSessionError is
+	// called when the session fails to load or save, the errors are logged when nil.
+	// A session failing to load starts over empty.
+	SessionStore   SessionStore
+	OnSessionError func(r *http.Request, err error)
+
+	// FlashKey is the prop flashed values are shared under.
+	FlashKey string
+
+	// VersionProvider computes the asset version, overriding the version passed to New
+	// until one is set with SetVersion or SetRelease. It is called once per request.
+	VersionProvider VersionProvider
+
+	// PropPrecedence lists the prop sources from lowest to highest precedence,
+	// DefaultPropPrecedence when empty. Each source must appear exactly once.
+	PropPrecedence []PropSource
+
+	// StrictProps reports props defined by more than one source: PrepareProps fails
+	// with a *PropConflictError, unless OnPropConflict is set, which is called instead.
+	StrictProps    bool
+	OnPropConflict func(r *http.Request, err *PropConflictError)
+
+	// ResolveWorkers bounds how many prop values are resolved concurrently, 1 resolves them one at a time.
+	ResolveWorkers int
+
+	// OnPropDegraded is called for every TimeoutProp that failed or timed out and
+	// was omitted or replaced by its fallback. It may be called concurrently.
+	OnPropDegraded func(r *http.Request, err *PropError)
+
+	// ErrorComponent is the component RenderError renders, "Error" by default.
+	// ErrorHandler responds to the errors passed to HandleError, it renders the
+	// error page with a 500 status when nil.
+	ErrorComponent string
+	ErrorHandler   func(w http.ResponseWriter, r *http.Request, err error)
+
+	// ErrorPages maps status codes to the component and props RenderError renders
+	// instead of ErrorComponent. With InterceptErrors, the Middleware renders them in
+	// place of the handler responses with those status codes, and of panics.
+	ErrorPages      map[int]ErrorPage
+	InterceptErrors bool
+
+	// RecoverPanics makes the Middleware recover panics of the handlers, implied by
+	// InterceptErrors. The error page of the 500 status is rendered, or with Debug an
+	// HTML page detailing the panic, the request and the props resolved so far.
+	// OnPanic is called with every recovered panic, they are logged when nil.
+	RecoverPanics bool
+	OnPanic       func(r *http.Request, p any, stack []byte)
+
+	// DevMode parses the root template again whenever it changes.
+	DevMode bool
+
+	// Debug keeps the detailed Go error of server errors and panics instead of rendering
+	// error pages. It exposes stack traces, request details and props: never enable it in production.
+	Debug bool
+
+	release atomic.Pointer[release]
 }
 
 // New function.
 func New(url, rootTemplate, version string) *Inertia {
-	return &Inertia{
-		Url:           url,
-		rootTemplate:  rootTemplate,
-		version:       version,
-		SharedFuncMap: template.FuncMap{"marshal": Marshal, "raw": Raw},
-		SharedProps:   Props{},
+	i := &Inertia{
+		Url:            url,
+		SharedFuncMap:  template.FuncMap{"marshal": Marshal, "raw": Raw},
+		SharedProps:    Props{},
+		FlashKey:       "flash",
+		ResolveWorkers: 8,
+		ErrorComponent: "Error",
 	}
+	i.release.Store(&release{version: version, rootTemplate: rootTemplate})
+
+	return i
 }
 
 // NewWithFS function.
@@ -64,81 +135,116 @@ func (i *Inertia) DisableSsr() {
 	i.SsrClient = nil
 }
 
-// Share function.
+// Share shares a prop with every page. It is safe for concurrent use.
 func (i *Inertia) Share(key string, value any) {
+	i.sharedMu.Lock()
+	defer i.sharedMu.Unlock()
+
 	i.SharedProps[key] = value
 }
 
-// ShareFunc function.
-func (i *Inertia) ShareFunc(key string, value any) {
-	i.SharedFuncMap[key] = value
+// ShareResolver shares a prop whose value is resolved for each request, only when the
+// prop is part of the response. It is safe for concurrent use.
+func (i *Inertia) ShareResolver(key string, resolver func(r *http.Request) (any, error)) {
+	i.Share(key, RequestResolver(resolver))
 }
 
-// WithProp function.
-func (i *Inertia) WithProp(ctx context.Context, key string, value any) context.Context {
-	contextProps := ctx.Value(ContextKeyProps)
+// SharedProp returns the shared prop stored under key. It is safe for concurrent use.
+func (i *Inertia) SharedProp(key string) (any, bool) {
+	i.sharedMu.RLock()
+	defer i.sharedMu.RUnlock()
 
-	if contextProps != nil {
-		contextProps, ok := contextProps.(Props)
-		if ok {
-			contextProps[key] = value
+	value, ok := i.SharedProps[key]
 
-			return context.WithValue(ctx, ContextKeyProps, contextProps)
-		}
+	return value, ok
+}
+
+// ShareAlways shares a prop that is included on every response, even during partial reloads.
+func (i *Inertia) ShareAlways(key string, value any) {
+	i.Share(key, Always(value))
+}
+
+// ShareFunc shares a function with the root template, which is parsed again on the next render.
+// It is safe for concurrent use.
+func (i *Inertia) ShareFunc(key string, value any) {
+	i.shareFuncs(template.FuncMap{key: value})
+}
+
+// shareFuncs adds the functions to SharedFuncMap and drops the parsed root template.
+func (i *Inertia) shareFuncs(funcs template.FuncMap) {
+	i.sharedMu.Lock()
+	for key, fn := range funcs {
+		i.SharedFuncMap[key] = fn
 	}
+	i.sharedMu.Unlock()
 
-	return context.WithValue(ctx, ContextKeyProps, Props{
-		key: value,
-	})
+	i.updateRelease(func(*release) {})
 }
 
-// WithProps appends props values to the passed context.Context.
+// WithProp returns a copy of ctx holding the prop along with the props of its ancestors.
+// The props of ctx itself are left untouched.
+func (i *Inertia) WithProp(ctx context.Context, key string, value any) context.Context {
+	return i.WithProps(ctx, Props{key: value})
+}
+
+// WithProps returns a copy of ctx holding the props along with the props of its ancestors.
+// The props of ctx itself, and the passed props map, are left untouched.
 func (i *Inertia) WithProps(ctx context.Context, props Props) context.Context {
-	if ctxData := ctx.Value(ContextKeyProps); ctxData != nil {
-		ctxData, ok := ctxData.(Props)
+	contextProps, _ := ctx.Value(ContextKeyProps).(Props)
 
-		if ok {
-			for key, val := range props {
-				ctxData[key] = val
-			}
+	return context.WithValue(ctx, ContextKeyProps, mergeProps(contextProps, props))
+}
 
-			return context.WithValue(ctx, ContextKeyProps, ctxData)
-		}
-	}
+// WithViewData returns a copy of ctx holding the view data along with the view data of its ancestors.
+// The view data of ctx itself is left untouched.
+func (i *Inertia) WithViewData(ctx context.Context, key string, value any) context.Context {
+	contextViewData, _ := ctx.Value(ContextKeyViewData).(Props)
 
-	return context.WithValue(ctx, ContextKeyProps, props)
+	return context.WithValue(ctx, ContextKeyViewData, mergeProps(contextViewData, Props{key: value}))
 }
 
-// WithViewData function.
-func (i *Inertia) WithViewData(ctx context.Context, key string, value any) context.Context {
-	contextViewData := ctx.Value(ContextKeyViewData)
+// mergeProps returns a new map holding the parent values overridden by the child values.
+func mergeProps(parent, child Props) Props {
+	merged := make(Props, len(parent)+len(child))
 
-	if contextViewData != nil {
-		contextViewData, ok := contextViewData.(Props)
-		if ok {
-			contextViewData[key] = value
+	for key, val := range parent {
+		merged[key] = val
+	}
 
-			return context.WithValue(ctx, ContextKeyViewData, contextViewData)
-		}
+	for key, val := range child {
+		merged[key] = val
 	}
 
-	return context.WithValue(ctx, ContextKeyViewData, Props{
-		key: value,
-	})
+	return merged
 }
 
 // Render function.
 func (i *Inertia) Render(w http.ResponseWriter, r *http.Request, component string, props Props) error {
+	return i.render(w, r, component, props, http.StatusOK)
+}
 
-	preparedProps, err := i.PrepareProps(r, component, props)
+// render writes the page with the status code, as JSON for Inertia requests and
+// within the root template otherwise. Nothing is written when the props fail to resolve.
+func (i *Inertia) render(w http.ResponseWriter, r *http.Request, component string, props Props, status int) error {
+	pin := i.requestRelease(r)
+	if pin.err != nil {
+		return pin.err
+	}
 
 	page := &Page{
-		Component: component,
-		URL:       r.RequestURI,
-		Version:   i.version,
-		Props:     preparedProps,
+		Component:      component,
+		URL:            r.RequestURI,
+		Version:        pin.version,
+		EncryptHistory: i.shouldEncryptHistory(r),
 	}
 
+	if err := i.preparePage(r, page, props); err != nil {
+		return err
+	}
+
+	page.ClearHistory = i.consumeClearHistory(w, r)
+	markRendered(r)
+
 	// Inertia request
 	if i.isInertiaRequest(r) {
 		js, err := json.Marshal(page)
@@ -149,6 +255,7 @@ func (i *Inertia) Render(w http.ResponseWriter, r *http.Request, component strin
 		w.Header().Set("Vary", "Accept")
 		w.Header().Set(Headers.Inertia, "true")
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
 
 		_, err = w.Write(js)
 		if err != nil {
@@ -185,12 +292,13 @@ func (i *Inertia) Render(w http.ResponseWriter, r *http.Request, component strin
 		viewData["ssr"] = nil
 	}
 
-	ts, err := i.createRootTemplate()
+	ts, err := i.loadRootTemplate(pin.rel)
 	if err != nil {
 		return err
 	}
 
 	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
 
 	err = ts.Execute(w, viewData)
 	if err != nil {
@@ -205,7 +313,7 @@ func (i *Inertia) Location(w http.ResponseWriter, r *http.Request, url string) {
 	if i.isInertiaRequest(r) {
 		w.Header().Set(Headers.Location, url)
 		w.WriteHeader(http.StatusConflict)
-	} else if r.Method == http.MethodPost || r.Method == http.MethodPatch || r.Method == http.MethodPut {
+	} else if r.Method == http.MethodPost || r.Method == http.MethodPatch || r.Method == http.MethodPut || r.Method == http.MethodDelete {
 		http.Redirect(w, r, url, http.StatusSeeOther)
 	} else {
 		http.Redirect(w, r, url, http.StatusFound)
@@ -220,16 +328,6 @@ func (i *Inertia) isInertiaRequest(r *http.Request) bool {
 	return r.Header.Get(Headers.Inertia) != ""
 }
 
-func (i *Inertia) createRootTemplate() (*template.Template, error) {
-	ts := template.New(filepath.Base(i.rootTemplate)).Funcs(i.SharedFuncMap)
-
-	if i.templateFS != nil {
-		return ts.ParseFS(i.templateFS, i.rootTemplate)
-	}
-
-	return ts.ParseFiles(i.rootTemplate)
-}
-
 func (i *Inertia) ssr(page *Page) (*Ssr, error) {
 	body, err := json.Marshal(page)
 	if err != nil {
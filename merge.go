@@ -0,0 +1,130 @@
+package inertia
+
+import "sort"
+
+// MergeProp is a property value the client appends to its current value
+// instead of replacing it, e.g. the next page of an infinite scrolling list.
+// Items whose MatchOn fields match an existing item replace it instead.
+//
+// https://inertiajs.com/merging-props
+type MergeProp struct {
+	Value   any
+	MatchOn []string
+}
+
+// PrependProp is a property value the client prepends to its current value.
+type PrependProp struct {
+	Value   any
+	MatchOn []string
+}
+
+// DeepMergeProp is a property value the client deeply merges into its current value.
+type DeepMergeProp struct {
+	Value   any
+	MatchOn []string
+}
+
+// Merge creates a MergeProp. The value may be a closure, LazyProp or DeferProp.
+func Merge(value any, matchOn ...string) MergeProp {
+	return MergeProp{Value: value, MatchOn: matchOn}
+}
+
+// Prepend creates a PrependProp. The value may be a closure, LazyProp or DeferProp.
+func Prepend(value any, matchOn ...string) PrependProp {
+	return PrependProp{Value: value, MatchOn: matchOn}
+}
+
+// DeepMerge creates a DeepMergeProp. The value may be a closure, LazyProp or DeferProp.
+func DeepMerge(value any, matchOn ...string) DeepMergeProp {
+	return DeepMergeProp{Value: value, MatchOn: matchOn}
+}
+
+type mergeStrategy int
+
+const (
+	mergeAppend mergeStrategy = iota
+	mergePrepend
+	mergeDeep
+)
+
+type mergeInfo struct {
+	strategy mergeStrategy
+	matchOn  []string
+}
+
+// unwrapMergeProps replaces merge prop wrappers with their values and
+// returns how each of the wrapped keys should be merged by the client.
+func unwrapMergeProps(props Props) map[string]mergeInfo {
+	merges := make(map[string]mergeInfo)
+
+	for key, val := range props {
+		var info mergeInfo
+
+		val, ok := unwrapNestedProp(val, func(val any) (any, bool) {
+			switch val := val.(type) {
+			case MergeProp:
+				info = mergeInfo{strategy: mergeAppend, matchOn: val.MatchOn}
+				return val.Value, true
+			case PrependProp:
+				info = mergeInfo{strategy: mergePrepend, matchOn: val.MatchOn}
+				return val.Value, true
+			case DeepMergeProp:
+				info = mergeInfo{strategy: mergeDeep, matchOn: val.MatchOn}
+				return val.Value, true
+			}
+
+			return nil, false
+		})
+
+		if ok {
+			merges[key] = info
+			props[key] = val
+		}
+	}
+
+	return merges
+}
+
+// advertiseMergeProps lists the merge props sent (or deferred) with the page,
+// except the ones the client asked to reset with the X-Inertia-Reset header.
+func (p *Page) advertiseMergeProps(merges map[string]mergeInfo, reset map[string]struct{}) {
+	for key, info := range merges {
+		if _, ok := reset[key]; ok {
+			continue
+		}
+
+		if _, ok := p.Props[key]; !ok && !p.isDeferred(key) {
+			continue
+		}
+
+		switch info.strategy {
+		case mergeAppend:
+			p.MergeProps = append(p.MergeProps, key)
+		case mergePrepend:
+			p.PrependProps = append(p.PrependProps, key)
+		case mergeDeep:
+			p.DeepMergeProps = append(p.DeepMergeProps, key)
+		}
+
+		for _, field := range info.matchOn {
+			p.MatchPropsOn = append(p.MatchPropsOn, key+"."+field)
+		}
+	}
+
+	sort.Strings(p.MergeProps)
+	sort.Strings(p.PrependProps)
+	sort.Strings(p.DeepMergeProps)
+	sort.Strings(p.MatchPropsOn)
+}
+
+func (p *Page) isDeferred(key string) bool {
+	for _, keys := range p.DeferredProps {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -1,23 +1,145 @@
 package inertia
 
-import "net/http"
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
 
 // Middleware function.
 func (i *Inertia) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Render with the assets of the release the version is checked against,
+		// even if a new release is swapped in meanwhile.
+		var pin *pinnedRelease
+		pin, r = i.withRelease(r)
+
+		r = i.withHistory(w, r)
+
+		if i.SessionStore != nil {
+			sw, sr := i.withSession(w, r)
+			defer sw.finish()
+
+			w, r = sw, sr
+		}
+
+		handler := next
+		if i.InterceptErrors || i.RecoverPanics {
+			handler = i.withErrorPages(next)
+		}
+
 		if r.Header.Get(Headers.Inertia) == "" {
-			next.ServeHTTP(w, r)
+			handler.ServeHTTP(w, r)
+
+			return
+		}
+
+		if pin.err != nil {
+			i.renderErrorPage(w, r, http.StatusInternalServerError, pin.err.Error())
 
 			return
 		}
 
-		if r.Method == "GET" && r.Header.Get(Headers.Version) != i.version {
+		if r.Method == "GET" && r.Header.Get(Headers.Version) != pin.version {
+			// The handler does not run, so flashed data is left in the session for the
+			// full page reload: it is only consumed when a page is rendered.
 			w.Header().Set(Headers.Location, i.Url+r.RequestURI)
 			w.WriteHeader(http.StatusConflict)
 
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		// Redirects following PUT, PATCH and DELETE requests must use 303,
+		// otherwise browsers replay the request method.
+		if r.Method == http.MethodPut || r.Method == http.MethodPatch || r.Method == http.MethodDelete {
+			w = &responseWriter{ResponseWriter: w, onWriteHeader: seeOther}
+		}
+
+		handler.ServeHTTP(w, r)
 	})
 }
+
+// seeOther converts 302 redirects to 303.
+func seeOther(code int) int {
+	if code == http.StatusFound {
+		return http.StatusSeeOther
+	}
+
+	return code
+}
+
+// responseWriter wraps http.ResponseWriter to adjust the status code right before the header is written.
+// The response is discarded when onWriteHeader returns 0.
+type responseWriter struct {
+	http.ResponseWriter
+	onWriteHeader func(code int) int
+	wroteHeader   bool
+	discard       bool
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+
+		if code = rw.onWriteHeader(code); code == 0 {
+			rw.discard = true
+		}
+	}
+
+	if rw.discard {
+		return
+	}
+
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// finish runs the header hook when the handler returned without writing a response.
+func (rw *responseWriter) finish() {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.onWriteHeader(http.StatusOK)
+	}
+}
+
+// written reports whether the response is on its way to the client.
+func (rw *responseWriter) written() bool {
+	return rw.wroteHeader && !rw.discard
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	if rw.discard {
+		return len(b), nil
+	}
+
+	return rw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, for streamed responses such as server-sent events.
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	if !rw.discard {
+		_ = http.NewResponseController(rw.ResponseWriter).Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, for websocket upgrades. The header hook does not run.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.wroteHeader = true
+	}
+
+	return conn, buf, err
+}
+
+// Unwrap returns the original http.ResponseWriter, see http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
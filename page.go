@@ -6,6 +6,14 @@ type Page struct {
 	Props     Props  `json:"props"`
 	URL       string `json:"url"`
 	Version   string `json:"version"`
+
+	DeferredProps  map[string][]string `json:"deferredProps,omitempty"`
+	MergeProps     []string            `json:"mergeProps,omitempty"`
+	PrependProps   []string            `json:"prependProps,omitempty"`
+	DeepMergeProps []string            `json:"deepMergeProps,omitempty"`
+	MatchPropsOn   []string            `json:"matchPropsOn,omitempty"`
+	EncryptHistory bool                `json:"encryptHistory,omitempty"`
+	ClearHistory   bool                `json:"clearHistory,omitempty"`
 }
 
 type Props = map[string]any
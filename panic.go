@@ -0,0 +1,157 @@
+package inertia
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// panicPage is the development error page shown for panics, which the Inertia client displays in its error modal.
+var panicPage = template.Must(template.New("panic").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>panic: {{ .Message }}</title>
+<style>
+body { margin: 0; padding: 2rem; font-family: ui-sans-serif, system-ui, sans-serif; color: #1f2937; background: #f9fafb; }
+h1 { margin-top: 0; font-size: 1.25rem; color: #b91c1c; word-break: break-word; }
+h2 { margin-top: 2rem; font-size: 1rem; }
+pre { padding: 1rem; overflow: auto; font-size: .8125rem; line-height: 1.5; background: #fff; border: 1px solid #e5e7eb; border-radius: .375rem; }
+table { border-collapse: collapse; font-size: .8125rem; }
+th, td { padding: .25rem 1rem .25rem 0; text-align: left; vertical-align: top; font-family: ui-monospace, monospace; }
+</style>
+</head>
+<body>
+<h1>panic: {{ .Message }}</h1>
+<h2>Request</h2>
+<table>
+<tr><th>Method</th><td>{{ .Method }}</td></tr>
+<tr><th>URL</th><td>{{ .URL }}</td></tr>
+<tr><th>Remote address</th><td>{{ .RemoteAddr }}</td></tr>
+{{ range .Headers }}<tr><th>{{ .Name }}</th><td>{{ .Value }}</td></tr>
+{{ end }}</table>
+<h2>Props</h2>
+{{ if .Props }}<pre>{{ .Props }}</pre>{{ else }}<p>No props were resolved.</p>{{ end }}
+<h2>Stack trace</h2>
+<pre>{{ .Stack }}</pre>
+</body>
+</html>
+`))
+
+type panicHeader struct {
+	Name  string
+	Value string
+}
+
+// recordPanicStack keeps the stack of the goroutine a panic started on, before it is
+// re-raised on the request goroutine. The first recorded stack wins.
+func recordPanicStack(r *http.Request) {
+	if state := errorPageStateFrom(r); state != nil {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		if state.stack == nil {
+			state.stack = debug.Stack()
+		}
+	}
+}
+
+// recordProps keeps the resolved props for the development panic page.
+func recordProps(r *http.Request, props Props) {
+	if state := errorPageStateFrom(r); state != nil {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+
+		state.props = props
+	}
+}
+
+// recoverPanic responds to a panic of the handler with the error page, or the panic page with Debug.
+func (i *Inertia) recoverPanic(w http.ResponseWriter, r *http.Request, p any, state *errorPageState) {
+	state.mu.Lock()
+	stack, props := state.stack, state.props
+	state.mu.Unlock()
+
+	if stack == nil {
+		stack = debug.Stack()
+	}
+
+	if i.OnPanic != nil {
+		i.OnPanic(r, p, stack)
+	} else {
+		log.Printf("inertia: panic serving %s: %v\n%s", r.URL, p, stack)
+	}
+
+	w.Header().Del("Content-Length")
+
+	if !i.Debug {
+		i.renderErrorPage(w, r, http.StatusInternalServerError, "")
+
+		return
+	}
+
+	// Not an Inertia response, so the client shows it in its error modal.
+	w.Header().Del(Headers.Inertia)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+
+	_ = panicPage.Execute(w, map[string]any{
+		"Message":    fmt.Sprint(p),
+		"Method":     r.Method,
+		"URL":        r.URL.String(),
+		"RemoteAddr": r.RemoteAddr,
+		"Headers":    panicHeaders(r),
+		"Props":      panicProps(props),
+		"Stack":      string(stack),
+	})
+}
+
+// sensitiveHeaders are the request headers holding credentials, redacted on the panic page.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+	"X-Csrf-Token":        true,
+	"X-Xsrf-Token":        true,
+}
+
+// panicHeaders returns the request headers sorted by name, with credentials redacted.
+func panicHeaders(r *http.Request) []panicHeader {
+	headers := make([]panicHeader, 0, len(r.Header))
+
+	for name, values := range r.Header {
+		value := strings.Join(values, ", ")
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			value = "[redacted]"
+		}
+
+		headers = append(headers, panicHeader{Name: name, Value: value})
+	}
+
+	sort.Slice(headers, func(a, b int) bool {
+		return headers[a].Name < headers[b].Name
+	})
+
+	return headers
+}
+
+// panicProps formats the props as indented JSON, falling back to the Go syntax representation.
+func panicProps(props Props) string {
+	if len(props) == 0 {
+		return ""
+	}
+
+	js, err := json.MarshalIndent(props, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%#v", props)
+	}
+
+	return string(js)
+}
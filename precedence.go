@@ -0,0 +1,140 @@
+package inertia
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// PropSource identifies where a prop is defined.
+type PropSource int
+
+const (
+	// SourceShared props are shared with Share, ShareAlways.
+	SourceShared PropSource = iota
+	// SourceContext props are added to the request context with WithProp, WithProps.
+	SourceContext
+	// SourceHandler props are passed to Render, PrepareProps.
+	SourceHandler
+)
+
+// DefaultPropPrecedence lets context props override shared props, and handler props override both.
+var DefaultPropPrecedence = []PropSource{SourceShared, SourceContext, SourceHandler}
+
+func (s PropSource) String() string {
+	switch s {
+	case SourceShared:
+		return "shared"
+	case SourceContext:
+		return "context"
+	case SourceHandler:
+		return "handler"
+	}
+
+	return fmt.Sprintf("PropSource(%d)", int(s))
+}
+
+// PropConflictError reports a prop defined by more than one source.
+type PropConflictError struct {
+	Key string
+	// Sources lists the sources defining the prop, the one whose value is used last.
+	Sources []PropSource
+}
+
+func (e *PropConflictError) Error() string {
+	sources := make([]string, len(e.Sources))
+	for n, source := range e.Sources {
+		sources[n] = source.String()
+	}
+
+	return fmt.Sprintf("inertia: prop %q is defined by several sources: %s", e.Key, strings.Join(sources, ", "))
+}
+
+func (e *PropConflictError) Unwrap() error {
+	return ErrPropConflict
+}
+
+// mergePropSources merges the shared, context and handler props into a new map,
+// following the PropPrecedence, and reports conflicts in StrictProps mode.
+func (i *Inertia) mergePropSources(r *http.Request, props Props) (Props, error) {
+	contextProps, ok := r.Context().Value(ContextKeyProps).(Props)
+	if !ok && r.Context().Value(ContextKeyProps) != nil {
+		return nil, ErrInvalidContextProps
+	}
+
+	i.sharedMu.RLock()
+	sharedProps := mergeProps(nil, i.SharedProps)
+	i.sharedMu.RUnlock()
+
+	sources := map[PropSource]Props{
+		SourceShared:  sharedProps,
+		SourceContext: contextProps,
+		SourceHandler: props,
+	}
+
+	precedence := i.PropPrecedence
+	if len(precedence) == 0 {
+		precedence = DefaultPropPrecedence
+	} else if err := validatePropPrecedence(precedence); err != nil {
+		return nil, err
+	}
+
+	merged := make(Props, len(sharedProps)+len(contextProps)+len(props))
+	defined := make(map[string][]PropSource)
+
+	for _, source := range precedence {
+		for key, val := range sources[source] {
+			merged[key] = val
+
+			if i.StrictProps {
+				defined[key] = append(defined[key], source)
+			}
+		}
+	}
+
+	return merged, i.checkPropConflicts(r, defined)
+}
+
+// validatePropPrecedence checks that every prop source appears exactly once,
+// so no source is silently dropped.
+func validatePropPrecedence(precedence []PropSource) error {
+	seen := make(map[PropSource]bool, len(precedence))
+
+	for _, source := range precedence {
+		if source < SourceShared || source > SourceHandler || seen[source] {
+			return fmt.Errorf("%w: %v", ErrInvalidPropPrecedence, precedence)
+		}
+		seen[source] = true
+	}
+
+	if len(seen) != len(DefaultPropPrecedence) {
+		return fmt.Errorf("%w: %v", ErrInvalidPropPrecedence, precedence)
+	}
+
+	return nil
+}
+
+// checkPropConflicts fails on the first conflicting key in alphabetical order,
+// or passes every conflict to OnPropConflict when it is set.
+func (i *Inertia) checkPropConflicts(r *http.Request, defined map[string][]PropSource) error {
+	keys := make([]string, 0)
+	for key, sources := range defined {
+		if len(sources) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		conflict := &PropConflictError{Key: key, Sources: defined[key]}
+
+		if i.OnPropConflict == nil {
+			return conflict
+		}
+
+		i.OnPropConflict(r, conflict)
+	}
+
+	return nil
+}
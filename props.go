@@ -1,8 +1,10 @@
 package inertia
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 )
 
@@ -11,76 +13,327 @@ import (
 // https://inertiajs.com/partial-reloads
 type LazyProp func() (any, error)
 
+// ContextResolver is a property value evaluated with the request context,
+// so the work behind it stops when the client goes away.
+type ContextResolver func(ctx context.Context) (any, error)
+
+// LazyContextProp is a LazyProp evaluated with the request context.
+//
+// https://inertiajs.com/partial-reloads
+type LazyContextProp func(ctx context.Context) (any, error)
+
+// RequestResolver is a property value evaluated with the current request,
+// e.g. the authenticated user shared with ShareResolver.
+type RequestResolver func(r *http.Request) (any, error)
+
+// AlwaysProp is a property value that is included on every response,
+// even during partial reloads that did not request it.
+//
+// https://inertiajs.com/partial-reloads
+type AlwaysProp struct {
+	Value any
+}
+
+// Always creates an AlwaysProp. The value may be a closure.
+func Always(value any) AlwaysProp {
+	return AlwaysProp{Value: value}
+}
+
+// DefaultDeferGroup is the group deferred props belong to when none is given.
+const DefaultDeferGroup = "default"
+
+// DeferProp is a property value that is omitted on the initial page load and
+// fetched by the client in a follow-up partial reload once the page has mounted.
+// Deferred props sharing a group are fetched together in a single request.
+// The resolver is a closure, ContextResolver or RequestResolver, so slow queries
+// can stop when the client goes away.
+//
+// https://inertiajs.com/deferred-props
+type DeferProp struct {
+	Group    string
+	Resolver any
+}
+
+// Defer creates a DeferProp, optionally assigned to the given group. The resolver is a
+// func() (any, error), func(context.Context) (any, error) or func(*http.Request) (any, error).
+func Defer(resolver any, group ...string) DeferProp {
+	prop := DeferProp{Group: DefaultDeferGroup, Resolver: normalizeResolver(resolver)}
+
+	if len(group) > 0 && group[0] != "" {
+		prop.Group = group[0]
+	}
+
+	return prop
+}
+
+// normalizeResolver converts func(context.Context) (any, error) and func(*http.Request) (any, error)
+// literals to a ContextResolver and a RequestResolver, other values are returned as is.
+func normalizeResolver(val any) any {
+	switch fn := val.(type) {
+	case func(ctx context.Context) (any, error):
+		return ContextResolver(fn)
+	case func(r *http.Request) (any, error):
+		return RequestResolver(fn)
+	}
+
+	return val
+}
+
+// PrepareProps merges shared, context and handler props, filters them for
+// partial reloads and resolves their values.
 func (i *Inertia) PrepareProps(r *http.Request, component string, props Props) (Props, error) {
+	page := &Page{Component: component}
+
+	if err := i.preparePage(r, page, props); err != nil {
+		return nil, err
+	}
+
+	return page.Props, nil
+}
+
+// preparePage fills the page props along with the prop metadata
+// (deferred and merge props) advertised to the client.
+func (i *Inertia) preparePage(r *http.Request, page *Page, props Props) error {
+	isPartial := r.Header.Get(Headers.PartialComponent) == page.Component
+
+	// Merge shared, context and handler props into a new map, the caller's map is left untouched.
+	props, err := i.mergePropSources(r, props)
+	if err != nil {
+		return err
+	}
+
+	if err = i.addDefaultProps(r, props); err != nil {
+		return err
+	}
+
+	// Unwrap always props, which survive partial reload filtering.
+	always := unwrapAlwaysProps(props)
 
-	if props == nil {
-		props = make(Props)
+	// Unwrap merge props, keeping track of how the client should merge them.
+	merges := unwrapMergeProps(props)
+
+	// Get props keys to return or exclude on partial reloads.
+	var only, except map[string]struct{}
+	if isPartial {
+		only = headerKeys(r, Headers.PartialOnly)
+		except = headerKeys(r, Headers.PartialExcept)
 	}
 
-	isPartial := r.Header.Get(Headers.PartialComponent) == component
+	// Filter props.
+	if len(only) > 0 || len(except) > 0 {
+		filterPartialProps(props, only, except, always)
+	} else {
+		page.DeferredProps = filterFirstLoadProps(props)
+	}
+
+	// Resolve props values.
+	if err = i.resolveProps(r, props); err != nil {
+		return err
+	}
 
-	// Merge props and shared props
-	for k, v := range i.SharedProps {
-		if _, ok := props[k]; !ok {
-			props[k] = v
+	page.Props = props
+	recordProps(r, props)
+	page.advertiseMergeProps(merges, headerKeys(r, Headers.Reset))
+
+	return nil
+}
+
+// addDefaultProps adds the validation errors and, when the request has a session,
+// the flashed data. Both are always included, even when empty.
+func (i *Inertia) addDefaultProps(r *http.Request, props Props) error {
+	if _, ok := props["errors"]; !ok {
+		errors, err := i.resolveErrors(r)
+		if err != nil {
+			return err
 		}
+		props["errors"] = Always(errors)
 	}
 
-	// Add props from context to the result.
-	contextProps := r.Context().Value(ContextKeyProps)
+	if _, ok := props[i.FlashKey]; !ok && SessionFromContext(r.Context()) != nil {
+		props[i.FlashKey] = Always(pullFlash(r))
+	}
+
+	return nil
+}
 
-	if contextProps != nil {
-		contextProps, ok := contextProps.(Props)
-		if !ok {
-			return nil, ErrInvalidContextProps
+// filterPartialProps keeps the props requested with the `only` property and
+// drops the ones listed with the `except` property. Except takes precedence over only.
+// Always props are kept regardless.
+func filterPartialProps(props Props, only, except, always map[string]struct{}) {
+	for key := range props {
+		if _, ok := always[key]; ok {
+			continue
 		}
 
-		for key, value := range contextProps {
-			props[key] = value
+		if _, ok := only[key]; !ok && len(only) > 0 {
+			delete(props, key)
+		}
+
+		if _, ok := except[key]; ok {
+			delete(props, key)
+		}
+	}
+}
+
+// unwrapAlwaysProps replaces always prop wrappers with their values and returns the wrapped keys.
+func unwrapAlwaysProps(props Props) map[string]struct{} {
+	always := make(map[string]struct{})
+
+	for key, val := range props {
+		val, ok := unwrapNestedProp(val, func(val any) (any, bool) {
+			prop, ok := val.(AlwaysProp)
+
+			return prop.Value, ok
+		})
+
+		if ok {
+			always[key] = struct{}{}
+			props[key] = val
 		}
 	}
 
-	// Get props keys to return. If len == 0, then return all.
-	partials := r.Header.Get(Headers.PartialOnly)
-	data := strings.Split(partials, ",")
-	only := make(map[string]struct{}, len(data))
+	return always
+}
+
+// unwrapNestedProp removes the wrapper matched by unwrap from the prop value, looking
+// through the always, merge and timeout wrappers around it, so Merge(Always(x)) and
+// Timeout(Always(x), d) are still always included.
+func unwrapNestedProp(val any, unwrap func(any) (any, bool)) (any, bool) {
+	if inner, ok := unwrap(val); ok {
+		// Drop repeated wrappers too, e.g. Always(Always(x)).
+		inner, _ = unwrapNestedProp(inner, unwrap)
+
+		return inner, true
+	}
+
+	var ok bool
 
-	if partials != "" && isPartial {
-		for _, value := range data {
-			only[value] = struct{}{}
+	switch prop := val.(type) {
+	case AlwaysProp:
+		if prop.Value, ok = unwrapNestedProp(prop.Value, unwrap); ok {
+			return prop, true
+		}
+	case MergeProp:
+		if prop.Value, ok = unwrapNestedProp(prop.Value, unwrap); ok {
+			return prop, true
+		}
+	case PrependProp:
+		if prop.Value, ok = unwrapNestedProp(prop.Value, unwrap); ok {
+			return prop, true
+		}
+	case DeepMergeProp:
+		if prop.Value, ok = unwrapNestedProp(prop.Value, unwrap); ok {
+			return prop, true
+		}
+	case TimeoutProp:
+		if prop.Value, ok = unwrapNestedProp(prop.Value, unwrap); ok {
+			return prop, true
 		}
 	}
 
-	// Filter props.
-	if len(only) > 0 {
-		// While making partials requests:
-		// Use the `only` property to specify which data the server should return.
-		for key := range props {
-			if _, ok := only[key]; !ok {
-				delete(props, key)
-			}
+	return val, false
+}
+
+// filterFirstLoadProps drops lazy and deferred props, which should only be
+// evaluated when explicitly requested, and returns the deferred keys by group.
+func filterFirstLoadProps(props Props) map[string][]string {
+	var deferred map[string][]string
+
+	for key, val := range props {
+		// A timeout wraps the lazy or deferred prop, it does not make it eager.
+		if timeout, ok := val.(TimeoutProp); ok {
+			val = timeout.Value
 		}
-	} else {
-		// Lazy props should only be evaluated when required using the `only` property
-		for key, val := range props {
-			if _, ok := val.(LazyProp); ok {
-				delete(props, key)
+
+		switch val := val.(type) {
+		case LazyProp, LazyContextProp:
+			delete(props, key)
+		case DeferProp:
+			if deferred == nil {
+				deferred = make(map[string][]string)
 			}
+			deferred[val.Group] = append(deferred[val.Group], key)
+			delete(props, key)
 		}
 	}
 
-	// Resolve props values.
-	for key, val := range props {
-		val, err := ResolvePropVal(val)
+	for _, keys := range deferred {
+		sort.Strings(keys)
+	}
+
+	return deferred
+}
+
+// headerKeys returns the set of prop keys listed in the given comma separated header.
+func headerKeys(r *http.Request, header string) map[string]struct{} {
+	keys := make(map[string]struct{})
+
+	value := r.Header.Get(header)
+	if value == "" {
+		return keys
+	}
+
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys[key] = struct{}{}
+		}
+	}
+
+	return keys
+}
+
+// resolveRequestPropVal resolves request resolvers with the request, other values with ResolvePropValContext.
+func resolveRequestPropVal(r *http.Request, val any) (any, error) {
+	val = normalizeResolver(val)
+
+	if deferred, ok := val.(DeferProp); ok {
+		return resolveRequestPropVal(r, deferred.Resolver)
+	}
+
+	if resolver, ok := val.(RequestResolver); ok {
+		if err := r.Context().Err(); err != nil {
+			return nil, err
+		}
+
+		val, err := resolver(r)
 		if err != nil {
-			return nil, fmt.Errorf("resolve prop value: %w", err)
+			return nil, fmt.Errorf("request prop resolving: %w", err)
 		}
-		props[key] = val
+
+		return val, nil
 	}
 
-	return props, nil
+	return ResolvePropValContext(r.Context(), val)
+}
+
+// ResolvePropValContext resolves the prop value like ResolvePropVal, passing ctx to context resolvers.
+// It returns the context error without resolving anything once ctx is done.
+func ResolvePropValContext(ctx context.Context, val any) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var err error
+
+	if deferred, ok := val.(DeferProp); ok {
+		return ResolvePropValContext(ctx, deferred.Resolver)
+	} else if resolver, ok := val.(ContextResolver); ok {
+		if val, err = resolver(ctx); err != nil {
+			return nil, fmt.Errorf("context prop resolving: %w", err)
+		}
+	} else if lazy, ok := val.(LazyContextProp); ok {
+		if val, err = lazy(ctx); err != nil {
+			return nil, fmt.Errorf("lazy prop resolving: %w", err)
+		}
+	} else {
+		return ResolvePropVal(val)
+	}
+
+	return val, nil
 }
 
+// ResolvePropVal resolves closures, lazy and deferred props to their value.
+// The resolver of a deferred prop is passed context.Background().
 func ResolvePropVal(val any) (any, error) {
 	var err error
 
@@ -97,6 +350,8 @@ func ResolvePropVal(val any) (any, error) {
 		if err != nil {
 			return nil, fmt.Errorf("lazy prop resolving: %w", err)
 		}
+	} else if deferred, ok := val.(DeferProp); ok {
+		return ResolvePropValContext(context.Background(), deferred.Resolver)
 	}
 
 	return val, nil
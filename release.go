@@ -0,0 +1,204 @@
+package inertia
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Release is a set of assets deployed together: the asset version, the root
+// template and the Vite build. See SetRelease, which keeps the current value of empty fields.
+type Release struct {
+	Version      string
+	RootTemplate string
+	Vite         *Vite
+}
+
+// release is the immutable snapshot of the current Release, along with the root template parsed for it.
+// A request renders with the snapshot it started with, even when a new one is swapped in meanwhile.
+type release struct {
+	version      string
+	rootTemplate string
+	vite         *Vite
+
+	// versioned reports whether the version was set by SetVersion or SetRelease,
+	// which takes precedence over the VersionProvider.
+	versioned bool
+
+	mu       sync.Mutex
+	template *template.Template
+	stamp    string
+}
+
+// SetVersion atomically swaps the asset version. Inertia requests made with
+// the previous version get a 409 response and reload the page.
+func (i *Inertia) SetVersion(version string) {
+	i.updateRelease(func(rel *release) {
+		rel.version = version
+		rel.versioned = true
+	})
+}
+
+// SetRelease atomically swaps the asset version, the root template and the Vite build,
+// those that are set, so requests never mix assets of different deploys.
+func (i *Inertia) SetRelease(next Release) {
+	i.updateRelease(func(rel *release) {
+		if next.Version != "" {
+			rel.version = next.Version
+			rel.versioned = true
+		}
+
+		if next.RootTemplate != "" {
+			rel.rootTemplate = next.RootTemplate
+		}
+
+		if next.Vite != nil {
+			rel.vite = next.Vite
+		}
+	})
+}
+
+// Vite returns the Vite build of the current release, see EnableVite.
+func (i *Inertia) Vite() *Vite {
+	return i.release.Load().vite
+}
+
+// updateRelease swaps in a copy of the current release changed by update.
+// The copy starts without a parsed root template.
+func (i *Inertia) updateRelease(update func(rel *release)) {
+	for {
+		current := i.release.Load()
+
+		next := &release{
+			version:      current.version,
+			rootTemplate: current.rootTemplate,
+			vite:         current.vite,
+			versioned:    current.versioned,
+		}
+		update(next)
+
+		if i.release.CompareAndSwap(current, next) {
+			return
+		}
+	}
+}
+
+// pinnedRelease is the release a request is served with, along with its asset version,
+// so the version checked by the Middleware is the one rendered.
+type pinnedRelease struct {
+	rel     *release
+	version string
+	err     error
+}
+
+// requestRelease returns the release the Middleware pinned to the request, else the current one.
+func (i *Inertia) requestRelease(r *http.Request) *pinnedRelease {
+	if pin, ok := r.Context().Value(contextKeyRelease).(*pinnedRelease); ok {
+		return pin
+	}
+
+	return i.pinRelease()
+}
+
+// withRelease pins the current release to the request.
+func (i *Inertia) withRelease(r *http.Request) (*pinnedRelease, *http.Request) {
+	pin := i.pinRelease()
+
+	return pin, r.WithContext(context.WithValue(r.Context(), contextKeyRelease, pin))
+}
+
+// pinRelease returns the current release along with its asset version.
+func (i *Inertia) pinRelease() *pinnedRelease {
+	rel := i.release.Load()
+	version, err := i.releaseVersion(rel)
+
+	return &pinnedRelease{rel: rel, version: version, err: err}
+}
+
+// releaseVersion returns the asset version of the release, from the VersionProvider
+// when set, unless the version was set by SetVersion or SetRelease.
+func (i *Inertia) releaseVersion(rel *release) (string, error) {
+	if i.VersionProvider != nil && !rel.versioned {
+		return i.VersionProvider()
+	}
+
+	return rel.version, nil
+}
+
+// loadRootTemplate returns the root template of the release, parsing it on first use
+// or, in DevMode, when the file changed.
+func (i *Inertia) loadRootTemplate(rel *release) (*template.Template, error) {
+	rel.mu.Lock()
+	defer rel.mu.Unlock()
+
+	stamp := ""
+	if i.DevMode {
+		var err error
+		if stamp, err = i.rootTemplateStamp(rel); err != nil {
+			return nil, err
+		}
+	}
+
+	if rel.template != nil && stamp == rel.stamp {
+		return rel.template, nil
+	}
+
+	ts, err := i.createRootTemplate(rel)
+	if err != nil {
+		return nil, err
+	}
+
+	rel.template = ts
+	rel.stamp = stamp
+
+	return ts, nil
+}
+
+// rootTemplateStamp identifies the current root template content: a hash of
+// the file for a templateFS, whose modification times may be zero, else the file modification time.
+func (i *Inertia) rootTemplateStamp(rel *release) (string, error) {
+	if i.templateFS != nil {
+		content, err := fs.ReadFile(i.templateFS, rel.rootTemplate)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%x", sha256.Sum256(content)), nil
+	}
+
+	info, err := os.Stat(rel.rootTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+func (i *Inertia) createRootTemplate(rel *release) (*template.Template, error) {
+	i.sharedMu.RLock()
+	funcs := make(template.FuncMap, len(i.SharedFuncMap)+2)
+	for key, fn := range i.SharedFuncMap {
+		funcs[key] = fn
+	}
+	i.sharedMu.RUnlock()
+
+	// Bind the Vite helpers to the build of this release.
+	if rel.vite != nil {
+		funcs["vite"] = rel.vite.Tags
+		funcs["viteAsset"] = rel.vite.Asset
+	}
+
+	ts := template.New(filepath.Base(rel.rootTemplate)).Funcs(funcs)
+
+	if i.templateFS != nil {
+		return ts.ParseFS(i.templateFS, rel.rootTemplate)
+	}
+
+	return ts.ParseFiles(rel.rootTemplate)
+}
@@ -0,0 +1,144 @@
+package inertia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// PropError reports the prop whose value could not be resolved.
+type PropError struct {
+	Key string
+	Err error
+}
+
+func (e *PropError) Error() string {
+	return fmt.Sprintf("inertia: resolve prop %q: %v", e.Key, e.Err)
+}
+
+func (e *PropError) Unwrap() error {
+	return e.Err
+}
+
+// resolveProps resolves the prop values concurrently, at most ResolveWorkers at once.
+// The first failure cancels the resolvers still running.
+func (i *Inertia) resolveProps(r *http.Request, props Props) error {
+	keys := make([]string, 0, len(props))
+	for key, val := range props {
+		if isResolver(val) {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	sort.Strings(keys)
+
+	parent := r.Context()
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	r = r.WithContext(ctx)
+	results, errs := make([]any, len(keys)), make([]error, len(keys))
+	resolved := make([]bool, len(keys))
+	sem := make(chan struct{}, max(i.ResolveWorkers, 1))
+
+	var wg sync.WaitGroup
+	var panicOnce sync.Once
+	var panicked any
+
+	for n, key := range keys {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(n int, key string, val any) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer func() {
+				// Re-panic on the request goroutine, where recovery middlewares can handle it.
+				if p := recover(); p != nil {
+					recordPanicStack(r)
+					panicOnce.Do(func() { panicked = p })
+					cancel()
+				}
+			}()
+
+			if results[n], errs[n] = i.resolvePropVal(r, key, val); errs[n] != nil {
+				cancel()
+			}
+			resolved[n] = true
+		}(n, key, props[key])
+	}
+
+	wg.Wait()
+
+	for n, key := range keys {
+		if _, ok := results[n].(omitted); ok || !resolved[n] || errs[n] != nil {
+			delete(props, key)
+		} else {
+			props[key] = results[n]
+		}
+	}
+
+	if panicked != nil {
+		// Keep the props resolved so far for the panic page.
+		recordProps(r, props)
+		panic(panicked)
+	}
+
+	return firstPropError(parent, keys, errs)
+}
+
+// resolvePropVal resolves the prop value, within its timeout for a TimeoutProp.
+// Deferred props are looked through, so Defer(Timeout(f, d)) keeps its timeout.
+func (i *Inertia) resolvePropVal(r *http.Request, key string, val any) (any, error) {
+	switch prop := val.(type) {
+	case TimeoutProp:
+		return i.resolveTimeoutProp(r, key, prop)
+	case DeferProp:
+		return i.resolvePropVal(r, key, prop.Resolver)
+	}
+
+	return resolveRequestPropVal(r, val)
+}
+
+// firstPropError returns the error of the first failed prop in key order, skipping
+// props that were only cancelled because another one failed.
+func firstPropError(parent context.Context, keys []string, errs []error) error {
+	var cancelled error
+
+	for n, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		propErr := &PropError{Key: keys[n], Err: err}
+
+		if errors.Is(err, context.Canceled) && parent.Err() == nil {
+			if cancelled == nil {
+				cancelled = propErr
+			}
+
+			continue
+		}
+
+		return propErr
+	}
+
+	return cancelled
+}
+
+// isResolver reports whether the prop value is computed by a function.
+func isResolver(val any) bool {
+	switch normalizeResolver(val).(type) {
+	case func() (any, error), LazyProp, DeferProp, ContextResolver, LazyContextProp, RequestResolver, TimeoutProp:
+		return true
+	}
+
+	return false
+}
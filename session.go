@@ -0,0 +1,139 @@
+package inertia
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// SessionStore loads and persists session values between requests.
+type SessionStore interface {
+	// Load returns the session values of the request, or an empty map for a new session.
+	Load(r *http.Request) (map[string]any, error)
+
+	// Save persists the session values, typically by setting a cookie on the response.
+	Save(w http.ResponseWriter, r *http.Request, values map[string]any) error
+}
+
+// Session holds the session values of a single request.
+type Session struct {
+	mu      sync.Mutex
+	values  map[string]any
+	changed bool
+}
+
+// NewSession creates a session holding the given values.
+func NewSession(values map[string]any) *Session {
+	if values == nil {
+		values = make(map[string]any)
+	}
+
+	return &Session{values: values}
+}
+
+// SessionFromContext returns the session the Middleware attached to the passed context.Context,
+// or nil when no SessionStore is configured.
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(ContextKeySession).(*Session)
+
+	return session
+}
+
+// Get returns the value stored under key.
+func (s *Session) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.values[key]
+}
+
+// Put stores the value under key.
+func (s *Session) Put(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+	s.changed = true
+}
+
+// Pull returns the value stored under key and removes it from the session.
+func (s *Session) Pull(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+
+	return value
+}
+
+// Delete removes the value stored under key.
+func (s *Session) Delete(key string) {
+	s.Pull(key)
+}
+
+// Values returns a copy of the session values.
+func (s *Session) Values() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values := make(map[string]any, len(s.values))
+	for key, value := range s.values {
+		values[key] = value
+	}
+
+	return values
+}
+
+// Changed reports whether the session values were modified.
+func (s *Session) Changed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.changed
+}
+
+// withSession loads the request session from the SessionStore and saves it
+// right before the response header is written.
+func (i *Inertia) withSession(w http.ResponseWriter, r *http.Request) (*responseWriter, *http.Request) {
+	values, err := i.SessionStore.Load(r)
+	if err != nil {
+		// Tampered, expired or undecodable sessions start over empty.
+		i.sessionError(r, "load", err)
+		values = nil
+	}
+
+	session := NewSession(values)
+	r = r.WithContext(context.WithValue(r.Context(), ContextKeySession, session))
+
+	rw := &responseWriter{
+		ResponseWriter: w,
+		onWriteHeader: func(code int) int {
+			if !session.Changed() {
+				return code
+			}
+
+			// The response is already on its way, a failing save can only be reported.
+			if err := i.SessionStore.Save(w, r, session.Values()); err != nil {
+				i.sessionError(r, "save", err)
+			}
+
+			return code
+		},
+	}
+
+	return rw, r
+}
+
+// sessionError reports a session that failed to load or save to OnSessionError, else logs it.
+func (i *Inertia) sessionError(r *http.Request, op string, err error) {
+	if i.OnSessionError != nil {
+		i.OnSessionError(r, err)
+	} else {
+		log.Printf("inertia: %s session for %s: %v", op, r.URL, err)
+	}
+}
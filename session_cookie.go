@@ -0,0 +1,223 @@
+package inertia
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxCookieSize is the size browsers are guaranteed to store for a single cookie.
+const maxCookieSize = 4096
+
+// CookieSessionStore stores the session values in an encrypted and signed cookie.
+//
+// Values are encrypted with AES-GCM and signed with HMAC-SHA256, using keys derived from
+// the configured secrets. The first secret is used to save sessions, all of them are
+// tried when loading, so secrets can be rotated by prepending a new one.
+type CookieSessionStore struct {
+	Name     string
+	Path     string
+	Domain   string
+	MaxAge   int
+	Secure   bool
+	SameSite http.SameSite
+
+	keys []cookieKeys
+}
+
+type cookieKeys struct {
+	encryption []byte
+	signing    []byte
+}
+
+type cookiePayload struct {
+	Values  map[string]any `json:"v"`
+	Expires int64          `json:"e,omitempty"`
+}
+
+// NewCookieSessionStore creates a CookieSessionStore using the given secrets, newest first.
+// It fails with ErrNoSessionKeys without secrets, and ErrEmptySessionSecret when one is empty.
+func NewCookieSessionStore(secrets ...[]byte) (*CookieSessionStore, error) {
+	if len(secrets) == 0 {
+		return nil, ErrNoSessionKeys
+	}
+
+	keys := make([]cookieKeys, 0, len(secrets))
+
+	for _, secret := range secrets {
+		if len(secret) == 0 {
+			return nil, ErrEmptySessionSecret
+		}
+
+		keys = append(keys, cookieKeys{
+			encryption: deriveKey(secret, "inertia session encryption"),
+			signing:    deriveKey(secret, "inertia session signing"),
+		})
+	}
+
+	return &CookieSessionStore{
+		Name:     "inertia_session",
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+		keys:     keys,
+	}, nil
+}
+
+// Load decrypts the session cookie.
+func (s *CookieSessionStore) Load(r *http.Request) (map[string]any, error) {
+	cookie, err := r.Cookie(s.Name)
+	if err != nil {
+		return make(map[string]any), nil
+	}
+
+	plaintext, err := s.decode(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload cookiePayload
+	if err = json.Unmarshal(plaintext, &payload); err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	if payload.Expires > 0 && time.Now().Unix() > payload.Expires {
+		return nil, ErrInvalidSession
+	}
+
+	if payload.Values == nil {
+		payload.Values = make(map[string]any)
+	}
+
+	return payload.Values, nil
+}
+
+// Save encrypts the session values into the session cookie.
+func (s *CookieSessionStore) Save(w http.ResponseWriter, r *http.Request, values map[string]any) error {
+	cookie := &http.Cookie{
+		Name:     s.Name,
+		Path:     s.Path,
+		Domain:   s.Domain,
+		MaxAge:   s.MaxAge,
+		Secure:   s.Secure,
+		HttpOnly: true,
+		SameSite: s.SameSite,
+	}
+
+	if len(values) == 0 {
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+
+		return nil
+	}
+
+	payload := cookiePayload{Values: values}
+	if s.MaxAge > 0 {
+		payload.Expires = time.Now().Add(time.Duration(s.MaxAge) * time.Second).Unix()
+	}
+
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	cookie.Value, err = s.encode(plaintext)
+	if err != nil {
+		return err
+	}
+
+	if len(cookie.String()) > maxCookieSize {
+		return ErrSessionTooLarge
+	}
+
+	http.SetCookie(w, cookie)
+
+	return nil
+}
+
+func (s *CookieSessionStore) encode(plaintext []byte) (string, error) {
+	if len(s.keys) == 0 {
+		return "", ErrNoSessionKeys
+	}
+
+	gcm, err := newGCM(s.keys[0].encryption)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, []byte(s.Name)))
+
+	return value + "." + s.sign(s.keys[0].signing, value), nil
+}
+
+func (s *CookieSessionStore) decode(cookieValue string) ([]byte, error) {
+	value, signature, ok := strings.Cut(cookieValue, ".")
+	if !ok {
+		return nil, ErrInvalidSession
+	}
+
+	for _, keys := range s.keys {
+		if !hmac.Equal([]byte(signature), []byte(s.sign(keys.signing, value))) {
+			continue
+		}
+
+		ciphertext, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return nil, ErrInvalidSession
+		}
+
+		gcm, err := newGCM(keys.encryption)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(ciphertext) < gcm.NonceSize() {
+			return nil, ErrInvalidSession
+		}
+
+		nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+
+		plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(s.Name))
+		if err != nil {
+			return nil, ErrInvalidSession
+		}
+
+		return plaintext, nil
+	}
+
+	return nil, ErrInvalidSession
+}
+
+func (s *CookieSessionStore) sign(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(s.Name + "=" + value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+
+	return mac.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
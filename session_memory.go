@@ -0,0 +1,73 @@
+package inertia
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+)
+
+// MemorySessionStore keeps the session values in memory, identified by a session id cookie.
+// It is meant for tests and single process development servers.
+type MemorySessionStore struct {
+	Name string
+
+	mu       sync.Mutex
+	sessions map[string]map[string]any
+}
+
+// NewMemorySessionStore creates an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		Name:     "inertia_session_id",
+		sessions: make(map[string]map[string]any),
+	}
+}
+
+// Load returns a copy of the session values stored for the request session id.
+func (s *MemorySessionStore) Load(r *http.Request) (map[string]any, error) {
+	values := make(map[string]any)
+
+	cookie, err := r.Cookie(s.Name)
+	if err != nil {
+		return values, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, value := range s.sessions[cookie.Value] {
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// Save stores the session values, creating a session id for new sessions.
+func (s *MemorySessionStore) Save(w http.ResponseWriter, r *http.Request, values map[string]any) error {
+	id := ""
+	if cookie, err := r.Cookie(s.Name); err == nil {
+		id = cookie.Value
+	}
+
+	if id == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		id = hex.EncodeToString(b)
+	}
+
+	stored := make(map[string]any, len(values))
+	for key, value := range values {
+		stored[key] = value
+	}
+
+	s.mu.Lock()
+	s.sessions[id] = stored
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: s.Name, Value: id, Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode})
+
+	return nil
+}
@@ -0,0 +1,355 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/humweb/inertia-go"
+	"github.com/stretchr/testify/suite"
+	"html"
+	"net/http"
+	"testing"
+)
+
+type InertiaErrorTestSuite struct {
+	suite.Suite
+}
+
+func (suite *InertiaErrorTestSuite) TestRenderPropagatesPropError() {
+	i := inertia.New("", "./index_test.html", "")
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	err := i.Render(w, r, "Users", inertia.Props{
+		"users": func() (any, error) {
+			return nil, errors.New("database down")
+		},
+	})
+
+	var propErr *inertia.PropError
+	suite.ErrorAs(err, &propErr)
+	suite.Equal("users", propErr.Key)
+	suite.Equal("", w.Body.String())
+}
+
+func (suite *InertiaErrorTestSuite) TestRenderErrorInertiaRequest() {
+	i := inertia.New("", "./index_test.html", "")
+	i.Share("app", "Go Inertia")
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	err := i.RenderError(w, r, http.StatusNotFound)
+	suite.Nil(err)
+	suite.Equal(http.StatusNotFound, w.Code)
+	suite.Equal("true", w.Header().Get("X-Inertia"))
+
+	var page inertia.Page
+	suite.Nil(json.Unmarshal(w.Body.Bytes(), &page))
+	suite.Equal("Error", page.Component)
+	suite.Equal(float64(http.StatusNotFound), page.Props["status"])
+	suite.Equal("Go Inertia", page.Props["app"])
+}
+
+func (suite *InertiaErrorTestSuite) TestRenderErrorFullPage() {
+	i := inertia.New("", "./index_test.html", "")
+	i.ErrorComponent = "Errors/Show"
+	w, r := mockRequest("GET", "/users", Headers{})
+
+	err := i.RenderError(w, r, http.StatusForbidden)
+	suite.Nil(err)
+	suite.Equal(http.StatusForbidden, w.Code)
+	suite.Equal("text/html", w.Header().Get("Content-Type"))
+	suite.Contains(html.UnescapeString(w.Body.String()), `"component":"Errors/Show"`)
+	suite.Contains(html.UnescapeString(w.Body.String()), `"status":403`)
+}
+
+func (suite *InertiaErrorTestSuite) TestHandleError() {
+	i := inertia.New("", "./index_test.html", "")
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	i.HandleError(w, r, errors.New("boom"))
+	suite.Equal(http.StatusInternalServerError, w.Code)
+
+	var page inertia.Page
+	suite.Nil(json.Unmarshal(w.Body.Bytes(), &page))
+	suite.Equal("Error", page.Component)
+	suite.Equal(float64(http.StatusInternalServerError), page.Props["status"])
+}
+
+func (suite *InertiaErrorTestSuite) TestHandleErrorHandler() {
+	i := inertia.New("", "./index_test.html", "")
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	errNotFound := errors.New("not found")
+	i.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errNotFound) {
+			status = http.StatusNotFound
+		}
+
+		suite.Nil(i.RenderError(w, r, status))
+	}
+
+	i.HandleError(w, r, errNotFound)
+	suite.Equal(http.StatusNotFound, w.Code)
+}
+
+func (suite *InertiaErrorTestSuite) TestHandleErrorFallback() {
+	i := inertia.New("", "./index_test.html", "")
+	i.ShareResolver("user", func(r *http.Request) (any, error) {
+		return nil, errors.New("database down")
+	})
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	err := i.Render(w, r, "Users", nil)
+	suite.NotNil(err)
+
+	i.HandleError(w, r, err)
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Equal("Internal Server Error\n", w.Body.String())
+}
+
+func (suite *InertiaErrorTestSuite) TestHandleErrorCanceled() {
+	i := inertia.New("", "./index_test.html", "")
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	ctx, cancel := context.WithCancel(r.Context())
+	cancel()
+
+	i.HandleError(w, r.WithContext(ctx), ctx.Err())
+	suite.Equal("", w.Body.String())
+}
+
+func (suite *InertiaErrorTestSuite) TestErrorPages() {
+	i := inertia.New("", "./index_test.html", "")
+	i.ErrorPages = map[int]inertia.ErrorPage{
+		http.StatusNotFound:  {Component: "Errors/NotFound", Props: inertia.Props{"title": "Page not found"}},
+		http.StatusForbidden: {Props: inertia.Props{"title": "Forbidden"}},
+	}
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	suite.Nil(i.RenderError(w, r, http.StatusNotFound))
+
+	page := suite.page(w.Body.Bytes())
+	suite.Equal("Errors/NotFound", page.Component)
+	suite.Equal(float64(http.StatusNotFound), page.Props["status"])
+	suite.Equal("Page not found", page.Props["title"])
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	suite.Nil(i.RenderError(w, r, http.StatusForbidden))
+
+	page = suite.page(w.Body.Bytes())
+	suite.Equal("Error", page.Component)
+	suite.Equal("Forbidden", page.Props["title"])
+}
+
+func (suite *InertiaErrorTestSuite) TestInterceptErrors() {
+	i := inertia.New("", "./index_test.html", "")
+	i.InterceptErrors = true
+	i.ErrorPages = map[int]inertia.ErrorPage{
+		http.StatusNotFound:            {Component: "Errors/NotFound"},
+		http.StatusInternalServerError: {},
+	}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("X-Test-Status") {
+		case "404":
+			http.NotFound(w, r)
+		case "500":
+			http.Error(w, "database down", http.StatusInternalServerError)
+		case "400":
+			http.Error(w, "bad input", http.StatusBadRequest)
+		}
+	}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Test-Status": "404"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusNotFound, w.Code)
+	suite.Equal("Errors/NotFound", suite.page(w.Body.Bytes()).Component)
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Test-Status": "500"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Equal("text/html", w.Header().Get("Content-Type"))
+	suite.Contains(html.UnescapeString(w.Body.String()), `"component":"Error"`)
+	suite.NotContains(w.Body.String(), "database down")
+
+	// Status codes without an error page are left alone.
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Test-Status": "400"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusBadRequest, w.Code)
+	suite.Equal("bad input\n", w.Body.String())
+}
+
+func (suite *InertiaErrorTestSuite) TestInterceptErrorsRendered() {
+	i := inertia.New("", "./index_test.html", "")
+	i.InterceptErrors = true
+	i.ErrorPages = map[int]inertia.ErrorPage{http.StatusNotFound: {}}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Nil(i.RenderError(w, r, http.StatusNotFound))
+	}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusNotFound, w.Code)
+	suite.Equal("Error", suite.page(w.Body.Bytes()).Component)
+}
+
+func (suite *InertiaErrorTestSuite) TestInterceptPanic() {
+	i := inertia.New("", "./index_test.html", "")
+	i.InterceptErrors = true
+	i.OnPanic = func(r *http.Request, p any, stack []byte) {}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusInternalServerError, w.Code)
+
+	page := suite.page(w.Body.Bytes())
+	suite.Equal("Error", page.Component)
+	suite.Equal(float64(http.StatusInternalServerError), page.Props["status"])
+}
+
+func (suite *InertiaErrorTestSuite) TestInterceptErrorsDebug() {
+	i := inertia.New("", "./index_test.html", "")
+	i.InterceptErrors = true
+	i.Debug = true
+	i.OnPanic = func(r *http.Request, p any, stack []byte) {}
+	i.ErrorPages = map[int]inertia.ErrorPage{
+		http.StatusNotFound:            {},
+		http.StatusInternalServerError: {},
+	}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("X-Test-Status") {
+		case "404":
+			http.NotFound(w, r)
+		case "500":
+			i.HandleError(w, r, errors.New("database down"))
+		default:
+			panic("boom")
+		}
+	}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Test-Status": "404"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusNotFound, w.Code)
+	suite.Equal("Error", suite.page(w.Body.Bytes()).Component)
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Test-Status": "500"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Equal("database down\n", w.Body.String())
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Contains(w.Body.String(), "panic: boom")
+	suite.Contains(w.Body.String(), "error_test.go")
+}
+
+func (suite *InertiaErrorTestSuite) TestRecoverPanics() {
+	i := inertia.New("", "./index_test.html", "")
+	i.RecoverPanics = true
+	// Reloading the root template does not expose error details.
+	i.DevMode = true
+	i.ErrorPages = map[int]inertia.ErrorPage{
+		http.StatusInternalServerError: {Component: "Errors/Server"},
+	}
+
+	var recovered any
+	var stack []byte
+	i.OnPanic = func(r *http.Request, p any, s []byte) {
+		recovered, stack = p, s
+	}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test-Status") == "404" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		panic("boom")
+	}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Equal("Errors/Server", suite.page(w.Body.Bytes()).Component)
+	suite.Equal("boom", recovered)
+	suite.Contains(string(stack), "error_test.go")
+
+	// Responses are only intercepted with InterceptErrors.
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Test-Status": "404"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusNotFound, w.Code)
+	suite.Equal("404 page not found\n", w.Body.String())
+}
+
+func (suite *InertiaErrorTestSuite) TestRecoverPanicsDebug() {
+	i := inertia.New("", "./index_test.html", "")
+	i.RecoverPanics = true
+	i.Debug = true
+	i.ResolveWorkers = 1
+	i.OnPanic = func(r *http.Request, p any, stack []byte) {}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = i.Render(w, r, "Users", inertia.Props{
+			"plan": "pro",
+			"team": func() (any, error) {
+				return "resolved-team", nil
+			},
+			"users": func() (any, error) {
+				panic("users exploded")
+			},
+		})
+	}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true", "Authorization": "Bearer s3cr3t"})
+	r.AddCookie(&http.Cookie{Name: "inertia_session", Value: "session-value"})
+	handler.ServeHTTP(w, r)
+
+	body := html.UnescapeString(w.Body.String())
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Equal("", w.Header().Get("X-Inertia"))
+	suite.Equal("text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	suite.Contains(body, "panic: users exploded")
+	suite.Contains(body, "/users")
+	suite.Contains(body, "X-Inertia")
+	suite.Contains(body, "[redacted]")
+	suite.NotContains(body, "s3cr3t")
+	suite.NotContains(body, "session-value")
+	suite.Contains(body, `"plan": "pro"`)
+	suite.Contains(body, `"team": "resolved-team"`)
+	suite.NotContains(body, `"users"`)
+	// The stack of the resolver goroutine, where the panic started.
+	suite.Contains(body, "created by github.com/humweb/inertia-go.(*Inertia).resolveProps")
+}
+
+func (suite *InertiaErrorTestSuite) TestRecoverPanicsAbort() {
+	i := inertia.New("", "./index_test.html", "")
+	i.RecoverPanics = true
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	suite.PanicsWithValue(http.ErrAbortHandler, func() {
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (suite *InertiaErrorTestSuite) page(body []byte) inertia.Page {
+	var page inertia.Page
+	suite.Nil(json.Unmarshal(body, &page))
+
+	return page
+}
+
+func TestInertiaErrorSuite(t *testing.T) {
+	suite.Run(t, new(InertiaErrorTestSuite))
+}
@@ -1,12 +1,15 @@
 package tests
 
 import (
+	"bufio"
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"github.com/humweb/inertia-go"
 	"github.com/stretchr/testify/suite"
 	"html"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -203,6 +206,302 @@ func (suite *InertiaHttpTestSuite) TestLazyPropsWithoutOnly() {
 	suite.Nil(page.Props["lazy"])
 }
 
+func (suite *InertiaHttpTestSuite) TestPartialOnlyExcept() {
+	cases := []struct {
+		name      string
+		component string
+		only      string
+		except    string
+		expected  []string
+	}{
+		{name: "full load", expected: []string{"title", "user", "foo", "errors"}},
+		{name: "only", component: "Users", only: "user,lazy", expected: []string{"user", "lazy", "errors"}},
+		{name: "except", component: "Users", except: "foo", expected: []string{"title", "user", "lazy", "errors"}},
+		{name: "except lazy", component: "Users", except: "lazy,title", expected: []string{"user", "foo", "errors"}},
+		{name: "only and except", component: "Users", only: "user,foo,lazy", except: "foo", expected: []string{"user", "lazy", "errors"}},
+		{name: "except wins over only", component: "Users", only: "user", except: "user", expected: []string{"errors"}},
+		{name: "other component", component: "Posts", only: "user", except: "foo", expected: []string{"title", "user", "foo", "errors"}},
+		{name: "whitespace", component: "Users", only: "user, lazy", except: " lazy", expected: []string{"user", "errors"}},
+	}
+
+	for _, tc := range cases {
+		suite.Run(tc.name, func() {
+			headers := Headers{"X-Inertia": "true"}
+			if tc.component != "" {
+				headers["X-Inertia-Partial-Component"] = tc.component
+			}
+			if tc.only != "" {
+				headers["X-Inertia-Partial-Data"] = tc.only
+			}
+			if tc.except != "" {
+				headers["X-Inertia-Partial-Except"] = tc.except
+			}
+			w, r := mockRequest("GET", "/users", headers)
+
+			i := inertia.New("", "", "")
+			i.Share("title", "Page title")
+
+			err := i.Render(w, r, "Users", inertia.Props{
+				"user": "foo",
+				"foo":  "bar",
+				"lazy": inertia.LazyProp(func() (any, error) {
+					return "lazyprop", nil
+				}),
+			})
+			suite.Nil(err)
+
+			var page inertia.Page
+			err = json.Unmarshal(w.Body.Bytes(), &page)
+			suite.Nil(err)
+
+			keys := make([]string, 0, len(page.Props))
+			for key := range page.Props {
+				keys = append(keys, key)
+			}
+			suite.ElementsMatch(tc.expected, keys)
+		})
+	}
+}
+
+func (suite *InertiaHttpTestSuite) TestErrors() {
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	i := inertia.New("", "", "")
+
+	err := i.Render(w, r, "Users", nil)
+	suite.Nil(err)
+
+	var page inertia.Page
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+	suite.Equal(map[string]interface{}{}, page.Props["errors"])
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	ctx := i.WithErrors(r.Context(), inertia.ValidationErrors{"name": "The name field is required."})
+	ctx = i.WithError(ctx, "email", "The email field is required.")
+
+	err = i.Render(w, r.WithContext(ctx), "Users", nil)
+	suite.Nil(err)
+
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+	suite.Equal(map[string]interface{}{
+		"name":  "The name field is required.",
+		"email": "The email field is required.",
+	}, page.Props["errors"])
+}
+
+func (suite *InertiaHttpTestSuite) TestErrorBag() {
+	w, r := mockRequest("GET", "/users", Headers{
+		"X-Inertia":           "true",
+		"X-Inertia-Error-Bag": "createUser",
+	})
+
+	i := inertia.New("", "", "")
+	ctx := i.WithError(r.Context(), "name", "The name field is required.")
+
+	err := i.Render(w, r.WithContext(ctx), "Users", nil)
+	suite.Nil(err)
+
+	var page inertia.Page
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+	suite.Equal(map[string]interface{}{
+		"createUser": map[string]interface{}{"name": "The name field is required."},
+	}, page.Props["errors"])
+}
+
+func (suite *InertiaHttpTestSuite) TestPreparePropsInvalidErrors() {
+	i := inertia.New("", "", "")
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	props, err := i.PrepareProps(r.WithContext(context.WithValue(r.Context(), inertia.ContextKeyErrors, 1)), "Users", nil)
+
+	suite.ErrorIs(err, inertia.ErrInvalidContextErrors)
+	suite.Nil(props)
+}
+
+func (suite *InertiaHttpTestSuite) TestDeferredProps() {
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	i := inertia.New("", "", "")
+	props := func() inertia.Props {
+		return inertia.Props{
+			"user": "foo",
+			"permissions": inertia.Defer(func() (any, error) {
+				return []string{"edit"}, nil
+			}),
+			"teams": inertia.Defer(func() (any, error) {
+				return "teams", nil
+			}, "sidebar"),
+			"projects": inertia.Defer(func() (any, error) {
+				return "projects", nil
+			}, "sidebar"),
+		}
+	}
+
+	err := i.Render(w, r, "Users", props())
+	suite.Nil(err)
+
+	var page inertia.Page
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+
+	suite.Equal("foo", page.Props["user"])
+	suite.NotContains(page.Props, "permissions")
+	suite.NotContains(page.Props, "teams")
+	suite.Equal(map[string][]string{
+		"default": {"permissions"},
+		"sidebar": {"projects", "teams"},
+	}, page.DeferredProps)
+
+	w, r = mockRequest("GET", "/users", Headers{
+		"X-Inertia":                   "true",
+		"X-Inertia-Partial-Component": "Users",
+		"X-Inertia-Partial-Data":      "teams,projects",
+	})
+
+	err = i.Render(w, r, "Users", props())
+	suite.Nil(err)
+
+	page = inertia.Page{}
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+
+	suite.Equal(inertia.Props{"teams": "teams", "projects": "projects", "errors": map[string]interface{}{}}, page.Props)
+	suite.Nil(page.DeferredProps)
+}
+
+func (suite *InertiaHttpTestSuite) TestMergeProps() {
+	props := func() inertia.Props {
+		return inertia.Props{
+			"posts": inertia.Merge([]string{"post"}, "id"),
+			"messages": inertia.Prepend(func() (any, error) {
+				return []string{"message"}, nil
+			}),
+			"settings": inertia.DeepMerge(inertia.Props{"theme": "dark"}),
+			"comments": inertia.Merge(inertia.Defer(func() (any, error) {
+				return []string{"comment"}, nil
+			})),
+		}
+	}
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	i := inertia.New("", "", "")
+
+	err := i.Render(w, r, "Users", props())
+	suite.Nil(err)
+
+	var page inertia.Page
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+
+	suite.Equal([]interface{}{"post"}, page.Props["posts"])
+	suite.Equal([]interface{}{"message"}, page.Props["messages"])
+	suite.Equal(map[string]interface{}{"theme": "dark"}, page.Props["settings"])
+	suite.NotContains(page.Props, "comments")
+	suite.Equal(map[string][]string{"default": {"comments"}}, page.DeferredProps)
+	suite.Equal([]string{"comments", "posts"}, page.MergeProps)
+	suite.Equal([]string{"messages"}, page.PrependProps)
+	suite.Equal([]string{"settings"}, page.DeepMergeProps)
+	suite.Equal([]string{"posts.id"}, page.MatchPropsOn)
+
+	w, r = mockRequest("GET", "/users", Headers{
+		"X-Inertia":                   "true",
+		"X-Inertia-Partial-Component": "Users",
+		"X-Inertia-Partial-Data":      "posts,messages",
+		"X-Inertia-Reset":             "posts",
+	})
+
+	err = i.Render(w, r, "Users", props())
+	suite.Nil(err)
+
+	page = inertia.Page{}
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+
+	suite.Equal(inertia.Props{
+		"posts":    []interface{}{"post"},
+		"messages": []interface{}{"message"},
+		"errors":   map[string]interface{}{},
+	}, page.Props)
+	suite.Nil(page.MergeProps)
+	suite.Equal([]string{"messages"}, page.PrependProps)
+	suite.Nil(page.DeepMergeProps)
+	suite.Nil(page.MatchPropsOn)
+}
+
+func (suite *InertiaHttpTestSuite) TestAlwaysProps() {
+	w, r := mockRequest("GET", "/users", Headers{
+		"X-Inertia":                   "true",
+		"X-Inertia-Partial-Component": "Users",
+		"X-Inertia-Partial-Data":      "user",
+		"X-Inertia-Partial-Except":    "flash",
+	})
+
+	i := inertia.New("", "", "")
+	i.Share("title", "Page title")
+	i.ShareAlways("auth", inertia.Props{"id": "1"})
+
+	ctx := i.WithProp(r.Context(), "flash", inertia.Always("Saved!"))
+	ctx = i.WithError(ctx, "name", "The name field is required.")
+
+	err := i.Render(w, r.WithContext(ctx), "Users", inertia.Props{
+		"user": "foo",
+		"foo":  "bar",
+		"count": inertia.Always(func() (any, error) {
+			return 1, nil
+		}),
+	})
+	suite.Nil(err)
+
+	var page inertia.Page
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+
+	suite.Equal(inertia.Props{
+		"user":   "foo",
+		"auth":   map[string]interface{}{"id": "1"},
+		"flash":  "Saved!",
+		"count":  float64(1),
+		"errors": map[string]interface{}{"name": "The name field is required."},
+	}, page.Props)
+}
+
+func (suite *InertiaHttpTestSuite) TestNestedWrappers() {
+	w, r := mockRequest("GET", "/users", Headers{
+		"X-Inertia":                   "true",
+		"X-Inertia-Partial-Component": "Users",
+		"X-Inertia-Partial-Data":      "user",
+	})
+
+	i := inertia.New("", "", "")
+
+	err := i.Render(w, r, "Users", inertia.Props{
+		"user":     "foo",
+		"count":    inertia.Merge(inertia.Always(1)),
+		"messages": inertia.Always(inertia.Prepend([]string{"message"})),
+		"settings": inertia.Always(inertia.Always(inertia.DeepMerge(inertia.Props{"theme": "dark"}))),
+	})
+	suite.Nil(err)
+
+	var page inertia.Page
+	err = json.Unmarshal(w.Body.Bytes(), &page)
+	suite.Nil(err)
+
+	suite.Equal(inertia.Props{
+		"user":     "foo",
+		"count":    float64(1),
+		"messages": []interface{}{"message"},
+		"settings": map[string]interface{}{"theme": "dark"},
+		"errors":   map[string]interface{}{},
+	}, page.Props)
+	suite.Equal([]string{"count"}, page.MergeProps)
+	suite.Equal([]string{"messages"}, page.PrependProps)
+	suite.Equal([]string{"settings"}, page.DeepMergeProps)
+}
+
 func (suite *InertiaHttpTestSuite) TestWithProp() {
 
 	w, r := mockRequest("GET", "/users", Headers{
@@ -299,6 +598,331 @@ func (suite *InertiaHttpTestSuite) TestMiddlewareRedirect() {
 
 }
 
+func (suite *InertiaHttpTestSuite) TestEncryptHistory() {
+	i := inertia.New("", "", "")
+	i.EncryptHistory = true
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	err := i.Render(w, r, "Users", nil)
+	suite.Nil(err)
+	suite.Contains(w.Body.String(), `"encryptHistory":true`)
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	err = i.Render(w, r.WithContext(i.WithEncryptHistory(r.Context(), false)), "Users", nil)
+	suite.Nil(err)
+	suite.NotContains(w.Body.String(), "encryptHistory")
+
+	i.EncryptHistory = false
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	i.EncryptHistoryMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Nil(i.Render(w, r, "Users", nil))
+	})).ServeHTTP(w, r)
+	suite.Contains(w.Body.String(), `"encryptHistory":true`)
+}
+
+func (suite *InertiaHttpTestSuite) TestClearHistory() {
+	i := inertia.New("", "", "")
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logout" {
+			i.ClearHistory(r.Context())
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+
+			return
+		}
+
+		suite.Nil(i.Render(w, r, "Login", nil))
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/logout", nil)
+	r.Header.Set("X-Inertia", "true")
+	handler.ServeHTTP(w, r)
+
+	suite.Equal(http.StatusSeeOther, w.Code)
+	cookies := w.Result().Cookies()
+	suite.Len(cookies, 1)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/login", nil)
+	r.Header.Set("X-Inertia", "true")
+	r.AddCookie(cookies[0])
+	handler.ServeHTTP(w, r)
+
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"clearHistory":true`)
+	suite.Equal(-1, w.Result().Cookies()[0].MaxAge)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/login", nil)
+	r.Header.Set("X-Inertia", "true")
+	handler.ServeHTTP(w, r)
+
+	suite.NotContains(w.Body.String(), "clearHistory")
+}
+
+func (suite *InertiaHttpTestSuite) TestClearHistoryUntilRendered() {
+	i := inertia.New("", "", "")
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/users":
+			_, _ = w.Write([]byte("[]"))
+		case "/logout":
+			i.ClearHistory(r.Context())
+			suite.Nil(i.Render(w, r, "Login", nil))
+		}
+	}))
+
+	// Requests that do not render a page leave the flag alone.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/users", nil)
+	r.AddCookie(&http.Cookie{Name: "inertia_clear_history", Value: "1"})
+	handler.ServeHTTP(w, r)
+
+	suite.Empty(w.Result().Cookies())
+
+	// A page rendered by the request clearing the history needs no cookie.
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/logout", nil)
+	r.Header.Set("X-Inertia", "true")
+	handler.ServeHTTP(w, r)
+
+	suite.Contains(w.Body.String(), `"clearHistory":true`)
+	suite.Empty(w.Result().Cookies())
+}
+
+func (suite *InertiaHttpTestSuite) TestMiddlewareFlusher() {
+	i := inertia.New("", "", "")
+	i.SessionStore = inertia.NewMemorySessionStore()
+	i.RecoverPanics = true
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		suite.True(ok)
+
+		_, _ = w.Write([]byte("data: ping\n\n"))
+		flusher.Flush()
+	}))
+
+	for _, method := range []string{"GET", "PUT"} {
+		w, r := mockRequest(method, "/events", Headers{"X-Inertia": "true"})
+		handler.ServeHTTP(w, r)
+
+		suite.True(w.Flushed, method)
+		suite.Equal("data: ping\n\n", w.Body.String())
+	}
+}
+
+func (suite *InertiaHttpTestSuite) TestMiddlewareHijacker() {
+	i := inertia.New("", "", "")
+	i.SessionStore = inertia.NewMemorySessionStore()
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		suite.True(ok)
+
+		_, _, err := hijacker.Hijack()
+		suite.Nil(err)
+	}))
+
+	w := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/socket", nil))
+
+	suite.True(w.hijacked)
+}
+
+func (suite *InertiaHttpTestSuite) TestMiddlewareSeeOther() {
+	i := inertia.New("", "", "")
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/users", http.StatusFound)
+	}))
+
+	cases := []struct {
+		method   string
+		inertia  bool
+		expected int
+	}{
+		{method: "PUT", inertia: true, expected: http.StatusSeeOther},
+		{method: "PATCH", inertia: true, expected: http.StatusSeeOther},
+		{method: "DELETE", inertia: true, expected: http.StatusSeeOther},
+		{method: "GET", inertia: true, expected: http.StatusFound},
+		{method: "DELETE", inertia: false, expected: http.StatusFound},
+	}
+
+	for _, tc := range cases {
+		headers := Headers{}
+		if tc.inertia {
+			headers["X-Inertia"] = "true"
+		}
+		w, r := mockRequest(tc.method, "/users", headers)
+
+		handler.ServeHTTP(w, r)
+
+		suite.Equal(tc.expected, w.Code, tc.method)
+		suite.Equal("/users", w.Header().Get("Location"))
+	}
+}
+
+func (suite *InertiaHttpTestSuite) TestLocationNonInertiaDeleteRequest() {
+	w, r := mockRequest("DELETE", "/users", Headers{})
+
+	i := inertia.New("", "", "")
+
+	i.Location(w, r, "/login")
+
+	suite.Equal(http.StatusSeeOther, w.Result().StatusCode)
+}
+
+func (suite *InertiaHttpTestSuite) TestPropPrecedence() {
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	i := inertia.New("", "", "")
+	i.Share("title", "shared")
+	i.Share("user", "shared")
+	r = r.WithContext(i.WithProps(r.Context(), inertia.Props{"user": "context", "team": "context"}))
+
+	props, err := i.PrepareProps(r, "Users", inertia.Props{"team": "handler"})
+	suite.Nil(err)
+	suite.Equal("shared", props["title"])
+	suite.Equal("context", props["user"])
+	suite.Equal("handler", props["team"])
+
+	i.PropPrecedence = []inertia.PropSource{inertia.SourceHandler, inertia.SourceShared, inertia.SourceContext}
+
+	props, err = i.PrepareProps(r, "Users", inertia.Props{"team": "handler", "title": "handler"})
+	suite.Nil(err)
+	suite.Equal("shared", props["title"])
+	suite.Equal("context", props["user"])
+	suite.Equal("context", props["team"])
+}
+
+func (suite *InertiaHttpTestSuite) TestInvalidPropPrecedence() {
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	i := inertia.New("", "", "")
+
+	for _, precedence := range [][]inertia.PropSource{
+		{inertia.SourceHandler},
+		{inertia.SourceShared, inertia.SourceContext, inertia.SourceHandler, inertia.SourceHandler},
+		{inertia.SourceShared, inertia.SourceHandler, inertia.SourceHandler},
+		{inertia.SourceShared, inertia.SourceContext, inertia.PropSource(7)},
+	} {
+		i.PropPrecedence = precedence
+
+		_, err := i.PrepareProps(r, "Users", nil)
+		suite.ErrorIs(err, inertia.ErrInvalidPropPrecedence, precedence)
+	}
+}
+
+func (suite *InertiaHttpTestSuite) TestStrictProps() {
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	i := inertia.New("", "", "")
+	i.StrictProps = true
+	i.Share("user", "shared")
+	r = r.WithContext(i.WithProp(r.Context(), "team", "context"))
+
+	props, err := i.PrepareProps(r, "Users", inertia.Props{"other": "handler"})
+	suite.Nil(err)
+	suite.Len(props, 4)
+
+	props, err = i.PrepareProps(r, "Users", inertia.Props{"user": "handler", "team": "handler"})
+	suite.Nil(props)
+	suite.ErrorIs(err, inertia.ErrPropConflict)
+
+	var conflict *inertia.PropConflictError
+	suite.ErrorAs(err, &conflict)
+	suite.Equal("team", conflict.Key)
+	suite.Equal([]inertia.PropSource{inertia.SourceContext, inertia.SourceHandler}, conflict.Sources)
+	suite.Equal(`inertia: prop "team" is defined by several sources: context, handler`, err.Error())
+
+	var conflicts []string
+	i.OnPropConflict = func(r *http.Request, err *inertia.PropConflictError) {
+		conflicts = append(conflicts, err.Error())
+	}
+
+	props, err = i.PrepareProps(r, "Users", inertia.Props{"user": "handler", "team": "handler"})
+	suite.Nil(err)
+	suite.Equal("handler", props["user"])
+	suite.Equal([]string{
+		`inertia: prop "team" is defined by several sources: context, handler`,
+		`inertia: prop "user" is defined by several sources: shared, handler`,
+	}, conflicts)
+}
+
+func (suite *InertiaHttpTestSuite) TestShareResolver() {
+	i := inertia.New("", "", "")
+
+	calls := 0
+	i.ShareResolver("auth", func(r *http.Request) (any, error) {
+		calls++
+
+		return r.Header.Get("X-User"), nil
+	})
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-User": "foo"})
+	props, err := i.PrepareProps(r, "Users", nil)
+	suite.Nil(err)
+	suite.Equal("foo", props["auth"])
+	suite.Equal(1, calls)
+
+	// Resolvers are skipped when partial reloads filter their prop out.
+	_, r = mockRequest("GET", "/users", Headers{
+		"X-Inertia":                   "true",
+		"X-Inertia-Partial-Component": "Users",
+		"X-Inertia-Partial-Data":      "user",
+	})
+	props, err = i.PrepareProps(r, "Users", inertia.Props{"user": "bar"})
+	suite.Nil(err)
+	suite.NotContains(props, "auth")
+	suite.Equal(1, calls)
+
+	i.ShareResolver("auth", func(r *http.Request) (any, error) {
+		return nil, errors.New("no session")
+	})
+
+	_, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	props, err = i.PrepareProps(r, "Users", nil)
+	suite.Nil(props)
+	suite.ErrorContains(err, "no session")
+}
+
+func (suite *InertiaHttpTestSuite) TestContextProps() {
+	i := inertia.New("", "", "")
+
+	props := inertia.Props{
+		"user": inertia.ContextResolver(func(ctx context.Context) (any, error) {
+			return "foo", ctx.Err()
+		}),
+		"lazy": inertia.LazyContextProp(func(ctx context.Context) (any, error) {
+			return "lazyprop", nil
+		}),
+	}
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	prepared, err := i.PrepareProps(r, "Users", props)
+	suite.Nil(err)
+	suite.Equal("foo", prepared["user"])
+	suite.NotContains(prepared, "lazy")
+
+	_, r = mockRequest("GET", "/users", Headers{
+		"X-Inertia":                   "true",
+		"X-Inertia-Partial-Component": "Users",
+		"X-Inertia-Partial-Data":      "lazy",
+	})
+	prepared, err = i.PrepareProps(r, "Users", props)
+	suite.Nil(err)
+	suite.Equal("lazyprop", prepared["lazy"])
+
+	// A client disconnect stops the resolution.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	prepared, err = i.PrepareProps(r.WithContext(ctx), "Users", props)
+	suite.Nil(prepared)
+	suite.ErrorIs(err, context.Canceled)
+}
+
 func (suite *InertiaHttpTestSuite) TestPreparePropsErrors() {
 	i := inertia.New("", "./index_test.html", "2")
 	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
@@ -325,6 +949,17 @@ func TestInertiaHttpSuite(t *testing.T) {
 	suite.Run(t, new(InertiaHttpTestSuite))
 }
 
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (w *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+
+	return nil, nil, nil
+}
+
 func mockRequest(method string, target string, headers Headers) (*httptest.ResponseRecorder, *http.Request) {
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(method, "/users", nil)
@@ -3,9 +3,16 @@ package tests
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/humweb/inertia-go"
 	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
 type InertiaTestSuite struct {
@@ -33,6 +40,66 @@ func (suite *InertiaTestSuite) TestShareFunc() {
 	//t.Error("expected: asset func, got: empty value")
 }
 
+func (suite *InertiaTestSuite) TestSharedProp() {
+	i := inertia.New("", "", "")
+	i.Share("title", "Page title")
+
+	title, ok := i.SharedProp("title")
+	suite.True(ok)
+	suite.Equal("Page title", title)
+
+	_, ok = i.SharedProp("missing")
+	suite.False(ok)
+}
+
+// TestShareConcurrently is meant to be run with the race detector.
+func (suite *InertiaTestSuite) TestShareConcurrently() {
+	path := filepath.Join(suite.T().TempDir(), "app.html")
+	suite.Nil(os.WriteFile(path, []byte(`{{ marshal .page }}`), 0o600))
+
+	i := inertia.New("", path, "")
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(3)
+
+		go func(n int) {
+			defer wg.Done()
+			for k := 0; k < 50; k++ {
+				i.Share(fmt.Sprintf("prop%d", n), k)
+				i.ShareAlways("always", k)
+			}
+		}(n)
+
+		go func(n int) {
+			defer wg.Done()
+			for k := 0; k < 50; k++ {
+				i.ShareFunc(fmt.Sprintf("func%d", n), func() int { return k })
+			}
+		}(n)
+
+		go func(n int) {
+			defer wg.Done()
+			for k := 0; k < 50; k++ {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest("GET", "/", nil)
+				if k%2 == 0 {
+					r.Header.Set("X-Inertia", "true")
+				}
+
+				suite.Nil(i.Render(w, r, "Home", inertia.Props{"n": n}))
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	for n := 0; n < 8; n++ {
+		val, ok := i.SharedProp(fmt.Sprintf("prop%d", n))
+		suite.True(ok)
+		suite.Equal(49, val)
+	}
+}
+
 func (suite *InertiaTestSuite) TestWithProp() {
 	ctx := context.TODO()
 
@@ -48,6 +115,61 @@ func (suite *InertiaTestSuite) TestWithProp() {
 	suite.Equal("test-user", user)
 }
 
+func (suite *InertiaTestSuite) TestWithPropCopyOnWrite() {
+	i := inertia.New("", "", "")
+
+	props := inertia.Props{"user": "parent-user"}
+	parent := i.WithProps(context.TODO(), props)
+	child := i.WithProp(parent, "team", "child-team")
+	sibling := i.WithProps(parent, inertia.Props{"user": "sibling-user"})
+
+	props["user"] = "changed"
+
+	suite.Equal(inertia.Props{"user": "parent-user"}, parent.Value(inertia.ContextKeyProps))
+	suite.Equal(inertia.Props{"user": "parent-user", "team": "child-team"}, child.Value(inertia.ContextKeyProps))
+	suite.Equal(inertia.Props{"user": "sibling-user"}, sibling.Value(inertia.ContextKeyProps))
+}
+
+func (suite *InertiaTestSuite) TestWithViewDataCopyOnWrite() {
+	i := inertia.New("", "", "")
+
+	parent := i.WithViewData(context.TODO(), "meta", "parent")
+	child := i.WithViewData(parent, "title", "child")
+
+	suite.Equal(inertia.Props{"meta": "parent"}, parent.Value(inertia.ContextKeyViewData))
+	suite.Equal(inertia.Props{"meta": "parent", "title": "child"}, child.Value(inertia.ContextKeyViewData))
+}
+
+func (suite *InertiaTestSuite) TestPreparePropsDoesNotMutate() {
+	i := inertia.New("", "", "")
+	i.Share("title", "Page title")
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Inertia", "true")
+	r.Header.Set("X-Inertia-Partial-Component", "Users")
+	r.Header.Set("X-Inertia-Partial-Data", "user")
+
+	ctxProps := inertia.Props{"foo": "bar"}
+	r = r.WithContext(i.WithProps(r.Context(), ctxProps))
+
+	props := inertia.Props{
+		"user": func() (any, error) {
+			return "resolved", nil
+		},
+		"other": "value",
+	}
+
+	prepared, err := i.PrepareProps(r, "Users", props)
+	suite.Nil(err)
+	suite.Equal("resolved", prepared["user"])
+	suite.NotContains(prepared, "other")
+
+	suite.Len(props, 2)
+	suite.Equal("value", props["other"])
+	suite.IsType(func() (any, error) { return nil, nil }, props["user"])
+	suite.Equal(inertia.Props{"foo": "bar"}, ctxProps)
+}
+
 func (suite *InertiaTestSuite) TestWithViewData() {
 	ctx := context.TODO()
 
@@ -98,6 +220,125 @@ func (suite *InertiaTestSuite) TestResolvePropsLazy() {
 
 }
 
+func (suite *InertiaTestSuite) TestResolvePropsDeferred() {
+
+	val, err := inertia.ResolvePropVal(inertia.Defer(func() (any, error) {
+		return "foo", nil
+	}))
+
+	suite.Equal("foo", val)
+	suite.Nil(err)
+
+	val, err = inertia.ResolvePropVal(inertia.Defer(func() (any, error) {
+		return nil, errors.New("nothing")
+	}, "group"))
+	suite.Error(err)
+	suite.Nil(val)
+
+}
+
+func (suite *InertiaTestSuite) TestResolvePropsContext() {
+	type ctxKey string
+	ctx := context.WithValue(context.Background(), ctxKey("user"), "foo")
+
+	val, err := inertia.ResolvePropValContext(ctx, inertia.ContextResolver(func(ctx context.Context) (any, error) {
+		return ctx.Value(ctxKey("user")), nil
+	}))
+	suite.Equal("foo", val)
+	suite.Nil(err)
+
+	val, err = inertia.ResolvePropValContext(ctx, inertia.LazyContextProp(func(ctx context.Context) (any, error) {
+		return nil, errors.New("nothing")
+	}))
+	suite.Error(err)
+	suite.Nil(val)
+
+	val, err = inertia.ResolvePropValContext(ctx, func() (any, error) {
+		return "closure", nil
+	})
+	suite.Equal("closure", val)
+	suite.Nil(err)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	called := false
+	val, err = inertia.ResolvePropValContext(cancelled, inertia.ContextResolver(func(ctx context.Context) (any, error) {
+		called = true
+
+		return "foo", nil
+	}))
+	suite.ErrorIs(err, context.Canceled)
+	suite.Nil(val)
+	suite.False(called)
+}
+
+func (suite *InertiaTestSuite) TestDeferResolvers() {
+	i := inertia.New("", "", "")
+
+	r := httptest.NewRequest("GET", "/users", nil)
+	r.Header.Set("X-Inertia", "true")
+	r.Header.Set("X-Inertia-Partial-Component", "Users")
+	r.Header.Set("X-Inertia-Partial-Data", "stats,user")
+
+	type ctxKey string
+	ctx, cancel := context.WithCancel(context.WithValue(r.Context(), ctxKey("team"), "core"))
+	r = r.WithContext(ctx)
+
+	props, err := i.PrepareProps(r, "Users", inertia.Props{
+		"stats": inertia.Defer(func(ctx context.Context) (any, error) {
+			return ctx.Value(ctxKey("team")), nil
+		}),
+		"user": inertia.Defer(func(r *http.Request) (any, error) {
+			return r.URL.Path, nil
+		}),
+	})
+	suite.Nil(err)
+	suite.Equal("core", props["stats"])
+	suite.Equal("/users", props["user"])
+
+	// The deferred resolver stops when the client goes away.
+	cancel()
+
+	_, err = i.PrepareProps(r, "Users", inertia.Props{
+		"stats": inertia.Defer(func(ctx context.Context) (any, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		}),
+	})
+	suite.ErrorIs(err, context.Canceled)
+}
+
+func (suite *InertiaTestSuite) TestFuncLiteralResolvers() {
+	i := inertia.New("", "", "")
+
+	type ctxKey string
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	r = r.WithContext(context.WithValue(r.Context(), ctxKey("team"), "core"))
+
+	props, err := i.PrepareProps(r, "Users", inertia.Props{
+		"team": func(ctx context.Context) (any, error) {
+			return ctx.Value(ctxKey("team")), nil
+		},
+		"path": func(r *http.Request) (any, error) {
+			return r.URL.Path, nil
+		},
+		"timed": inertia.Timeout(func(ctx context.Context) (any, error) {
+			return "timed", nil
+		}, time.Second),
+	})
+	suite.Nil(err)
+	suite.Equal("core", props["team"])
+	suite.Equal("/users", props["path"])
+	suite.Equal("timed", props["timed"])
+}
+
+func (suite *InertiaTestSuite) TestDeferGroup() {
+	suite.Equal(inertia.DefaultDeferGroup, inertia.Defer(nil).Group)
+	suite.Equal("sidebar", inertia.Defer(nil, "sidebar").Group)
+}
+
 func TestInertiaSuite(t *testing.T) {
 	suite.Run(t, new(InertiaTestSuite))
 }
@@ -0,0 +1,351 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/humweb/inertia-go"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type InertiaResolveTestSuite struct {
+	suite.Suite
+}
+
+func (suite *InertiaResolveTestSuite) TestParallel() {
+	i := inertia.New("", "", "")
+
+	// Every resolver waits for all of them to be running, which only happens when they run at once.
+	var barrier sync.WaitGroup
+	barrier.Add(5)
+	opened := make(chan struct{})
+	go func() {
+		barrier.Wait()
+		close(opened)
+	}()
+
+	parallel := func(val string) inertia.ContextResolver {
+		return func(ctx context.Context) (any, error) {
+			barrier.Done()
+
+			select {
+			case <-opened:
+				return val, nil
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("resolvers did not run in parallel")
+			}
+		}
+	}
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	prepared, err := i.PrepareProps(r, "Users", inertia.Props{
+		"a": parallel("a"), "b": parallel("b"), "c": parallel("c"), "d": parallel("d"), "e": parallel("e"), "plain": "value",
+	})
+	suite.Nil(err)
+	suite.Equal("a", prepared["a"])
+	suite.Equal("e", prepared["e"])
+	suite.Equal("value", prepared["plain"])
+}
+
+func (suite *InertiaResolveTestSuite) TestWorkerLimit() {
+	i := inertia.New("", "", "")
+	i.ResolveWorkers = 2
+
+	var running, peak atomic.Int32
+
+	// Resolvers pair up before returning, so two of them run at once, never more.
+	pair := make(chan struct{})
+	paired := func(val string) inertia.ContextResolver {
+		return func(ctx context.Context) (any, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				current := peak.Load()
+				if n <= current || peak.CompareAndSwap(current, n) {
+					break
+				}
+			}
+
+			select {
+			case pair <- struct{}{}:
+			case <-pair:
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("resolvers did not run in parallel")
+			}
+
+			return val, nil
+		}
+	}
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	prepared, err := i.PrepareProps(r, "Users", inertia.Props{
+		"a": paired("a"), "b": paired("b"), "c": paired("c"), "d": paired("d"),
+	})
+	suite.Nil(err)
+	suite.Equal(int32(2), peak.Load())
+	suite.Equal("c", prepared["c"])
+}
+
+func (suite *InertiaResolveTestSuite) TestFirstErrorCancels() {
+	i := inertia.New("", "", "")
+
+	running, cancelled := make(chan struct{}), make(chan struct{})
+	props := inertia.Props{
+		"a": inertia.ContextResolver(func(ctx context.Context) (any, error) {
+			close(running)
+
+			select {
+			case <-ctx.Done():
+				close(cancelled)
+
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return "a", nil
+			}
+		}),
+		"b": func() (any, error) {
+			<-running
+
+			return nil, errors.New("query failed")
+		},
+	}
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	prepared, err := i.PrepareProps(r, "Users", props)
+	suite.Nil(prepared)
+
+	var propErr *inertia.PropError
+	suite.ErrorAs(err, &propErr)
+	suite.Equal("b", propErr.Key)
+	suite.EqualError(err, `inertia: resolve prop "b": closure prop resolving: query failed`)
+
+	select {
+	case <-cancelled:
+	default:
+		suite.Fail("expected the running resolver to be cancelled")
+	}
+}
+
+func (suite *InertiaResolveTestSuite) TestDeterministicError() {
+	i := inertia.New("", "", "")
+
+	for n := 0; n < 20; n++ {
+		// Every resolver is running when they fail, the first key is reported.
+		var started sync.WaitGroup
+		started.Add(3)
+
+		failing := func(msg string) func() (any, error) {
+			return func() (any, error) {
+				started.Done()
+				started.Wait()
+
+				return nil, errors.New(msg)
+			}
+		}
+
+		_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+		_, err := i.PrepareProps(r, "Users", inertia.Props{"c": failing("c"), "a": failing("a"), "b": failing("b")})
+
+		var propErr *inertia.PropError
+		suite.ErrorAs(err, &propErr)
+		suite.Equal("a", propErr.Key)
+	}
+
+	// Resolved one at a time, the resolvers after the first failure are cancelled.
+	i.ResolveWorkers = 1
+
+	calls := 0
+	failing := func() (any, error) {
+		calls++
+
+		return nil, errors.New("failed")
+	}
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	_, err := i.PrepareProps(r, "Users", inertia.Props{"c": failing, "a": failing, "b": failing})
+
+	var propErr *inertia.PropError
+	suite.ErrorAs(err, &propErr)
+	suite.Equal("a", propErr.Key)
+	suite.Equal(1, calls)
+}
+
+func (suite *InertiaResolveTestSuite) TestPanic() {
+	i := inertia.New("", "", "")
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	suite.PanicsWithValue("boom", func() {
+		_, _ = i.PrepareProps(r, "Users", inertia.Props{
+			"a": func() (any, error) {
+				panic("boom")
+			},
+		})
+	})
+}
+
+func (suite *InertiaResolveTestSuite) TestTimeout() {
+	i := inertia.New("", "", "")
+
+	var mu sync.Mutex
+	var degraded []string
+	i.OnPropDegraded = func(r *http.Request, err *inertia.PropError) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		degraded = append(degraded, err.Key)
+		suite.ErrorIs(err, context.DeadlineExceeded)
+	}
+
+	// Ignores its context and blocks until the test ends, the page does not wait for it.
+	release := make(chan struct{})
+	suite.T().Cleanup(func() { close(release) })
+
+	slow := func() (any, error) {
+		<-release
+
+		return "slow", nil
+	}
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+
+	props, err := i.PrepareProps(r, "Users", inertia.Props{
+		"fast":     inertia.Timeout(func() (any, error) { return "fast", nil }, time.Second),
+		"omitted":  inertia.Timeout(slow, 20*time.Millisecond).OrOmit(),
+		"fallback": inertia.Timeout(slow, 20*time.Millisecond).OrFallback([]string{}),
+	})
+	suite.Nil(err)
+
+	suite.Equal("fast", props["fast"])
+	suite.NotContains(props, "omitted")
+	suite.Equal([]string{}, props["fallback"])
+	suite.ElementsMatch([]string{"omitted", "fallback"}, degraded)
+}
+
+func (suite *InertiaResolveTestSuite) TestTimeoutFailPage() {
+	i := inertia.New("", "", "")
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	props, err := i.PrepareProps(r, "Users", inertia.Props{
+		"widget": inertia.Timeout(inertia.ContextResolver(func(ctx context.Context) (any, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		}), 20*time.Millisecond),
+	})
+	suite.Nil(props)
+
+	var propErr *inertia.PropError
+	suite.ErrorAs(err, &propErr)
+	suite.Equal("widget", propErr.Key)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (suite *InertiaResolveTestSuite) TestTimeoutErrorFallback() {
+	i := inertia.New("", "", "")
+
+	_, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	props, err := i.PrepareProps(r, "Users", inertia.Props{
+		"widget": inertia.Timeout(func() (any, error) {
+			return nil, errors.New("widget down")
+		}, time.Second).OrFallback("n/a"),
+	})
+	suite.Nil(err)
+	suite.Equal("n/a", props["widget"])
+}
+
+func (suite *InertiaResolveTestSuite) TestTimeoutDeferred() {
+	i := inertia.New("", "", "")
+
+	var calls atomic.Int32
+	props := func() inertia.Props {
+		return inertia.Props{
+			"stats": inertia.Timeout(inertia.Defer(func() (any, error) {
+				calls.Add(1)
+
+				return "stats", nil
+			}, "widgets"), time.Second).OrOmit(),
+			"lazy": inertia.Timeout(inertia.LazyProp(func() (any, error) {
+				calls.Add(1)
+
+				return "lazy", nil
+			}), time.Second),
+		}
+	}
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	suite.Nil(i.Render(w, r, "Users", props()))
+
+	var page inertia.Page
+	suite.Nil(json.Unmarshal(w.Body.Bytes(), &page))
+	suite.NotContains(page.Props, "stats")
+	suite.NotContains(page.Props, "lazy")
+	suite.Equal(map[string][]string{"widgets": {"stats"}}, page.DeferredProps)
+	suite.Equal(int32(0), calls.Load())
+
+	_, r = mockRequest("GET", "/users", Headers{
+		"X-Inertia":                   "true",
+		"X-Inertia-Partial-Component": "Users",
+		"X-Inertia-Partial-Data":      "stats,lazy",
+	})
+	prepared, err := i.PrepareProps(r, "Users", props())
+	suite.Nil(err)
+	suite.Equal("stats", prepared["stats"])
+	suite.Equal("lazy", prepared["lazy"])
+	suite.Equal(int32(2), calls.Load())
+}
+
+func (suite *InertiaResolveTestSuite) TestTimeoutNesting() {
+	i := inertia.New("", "", "")
+
+	props := func() inertia.Props {
+		return inertia.Props{
+			"user": inertia.Timeout(inertia.Always(func() (any, error) { return "jane", nil }), time.Second),
+			"posts": inertia.Timeout(inertia.Merge(func() (any, error) {
+				return []string{"post"}, nil
+			}, "id"), time.Second),
+			"stats": inertia.Defer(inertia.Timeout(func() (any, error) { return "stats", nil }, time.Second)),
+			"widget": inertia.Defer(inertia.Timeout(inertia.ContextResolver(func(ctx context.Context) (any, error) {
+				<-ctx.Done()
+
+				return nil, ctx.Err()
+			}), 20*time.Millisecond).OrOmit()),
+		}
+	}
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	suite.Nil(i.Render(w, r, "Users", props()))
+
+	var page inertia.Page
+	suite.Nil(json.Unmarshal(w.Body.Bytes(), &page))
+	suite.Equal("jane", page.Props["user"])
+	suite.Equal([]any{"post"}, page.Props["posts"])
+	suite.Equal([]string{"posts"}, page.MergeProps)
+	suite.Equal([]string{"posts.id"}, page.MatchPropsOn)
+	suite.Equal(map[string][]string{"default": {"stats", "widget"}}, page.DeferredProps)
+
+	_, r = mockRequest("GET", "/users", Headers{
+		"X-Inertia":                   "true",
+		"X-Inertia-Partial-Component": "Users",
+		"X-Inertia-Partial-Data":      "stats,widget",
+	})
+	prepared, err := i.PrepareProps(r, "Users", props())
+	suite.Nil(err)
+	suite.Equal("jane", prepared["user"])
+	suite.Equal("stats", prepared["stats"])
+	suite.NotContains(prepared, "posts")
+	suite.NotContains(prepared, "widget")
+}
+
+func TestInertiaResolveSuite(t *testing.T) {
+	suite.Run(t, new(InertiaResolveTestSuite))
+}
@@ -0,0 +1,339 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/humweb/inertia-go"
+	"github.com/stretchr/testify/suite"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type InertiaSessionTestSuite struct {
+	suite.Suite
+}
+
+func (suite *InertiaSessionTestSuite) TestSession() {
+	session := inertia.NewSession(nil)
+	suite.False(session.Changed())
+
+	session.Put("foo", "bar")
+	suite.True(session.Changed())
+	suite.Equal("bar", session.Get("foo"))
+
+	suite.Equal("bar", session.Pull("foo"))
+	suite.Nil(session.Get("foo"))
+
+	session.Put("abc", "123")
+	session.Delete("abc")
+	suite.Empty(session.Values())
+}
+
+func (suite *InertiaSessionTestSuite) TestCookieSessionStore() {
+	store := suite.cookieStore("secret")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	values, err := store.Load(r)
+	suite.Nil(err)
+	suite.Empty(values)
+
+	err = store.Save(w, r, map[string]any{"foo": "bar"})
+	suite.Nil(err)
+
+	cookie := w.Result().Cookies()[0]
+	suite.Equal("inertia_session", cookie.Name)
+	suite.True(cookie.HttpOnly)
+	suite.NotContains(cookie.Value, "bar")
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+
+	values, err = store.Load(r)
+	suite.Nil(err)
+	suite.Equal(map[string]any{"foo": "bar"}, values)
+
+	// Rotated secrets still load sessions saved with the previous secret.
+	rotated := suite.cookieStore("new-secret", "secret")
+	values, err = rotated.Load(r)
+	suite.Nil(err)
+	suite.Equal(map[string]any{"foo": "bar"}, values)
+
+	_, err = suite.cookieStore("other").Load(r)
+	suite.ErrorIs(err, inertia.ErrInvalidSession)
+}
+
+func (suite *InertiaSessionTestSuite) TestCookieSessionStoreTampered() {
+	store := suite.cookieStore("secret")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	suite.Nil(store.Save(w, r, map[string]any{"foo": "bar"}))
+
+	cookie := w.Result().Cookies()[0]
+	cookie.Value = "x" + cookie.Value[1:]
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+
+	_, err := store.Load(r)
+	suite.ErrorIs(err, inertia.ErrInvalidSession)
+}
+
+func (suite *InertiaSessionTestSuite) TestCookieSessionStoreErrors() {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	_, err := inertia.NewCookieSessionStore()
+	suite.ErrorIs(err, inertia.ErrNoSessionKeys)
+
+	_, err = inertia.NewCookieSessionStore([]byte("secret"), nil)
+	suite.ErrorIs(err, inertia.ErrEmptySessionSecret)
+
+	err = (&inertia.CookieSessionStore{Name: "inertia_session"}).Save(w, r, map[string]any{"foo": "bar"})
+	suite.ErrorIs(err, inertia.ErrNoSessionKeys)
+
+	err = suite.cookieStore("secret").Save(w, r, map[string]any{"foo": strings.Repeat("a", 5000)})
+	suite.ErrorIs(err, inertia.ErrSessionTooLarge)
+}
+
+func (suite *InertiaSessionTestSuite) TestMemorySessionStore() {
+	store := inertia.NewMemorySessionStore()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	suite.Nil(store.Save(w, r, map[string]any{"foo": "bar"}))
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(w.Result().Cookies()[0])
+
+	values, err := store.Load(r)
+	suite.Nil(err)
+	suite.Equal(map[string]any{"foo": "bar"}, values)
+}
+
+func (suite *InertiaSessionTestSuite) TestMiddlewareSession() {
+	i := inertia.New("", "", "")
+	i.SessionStore = suite.cookieStore("secret")
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := inertia.SessionFromContext(r.Context())
+		suite.NotNil(session)
+
+		count, _ := session.Get("count").(float64)
+		session.Put("count", count+1)
+	}))
+
+	var cookies []*http.Cookie
+	for n := 1; n <= 3; n++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+
+		handler.ServeHTTP(w, r)
+
+		cookies = w.Result().Cookies()
+		suite.Len(cookies, 1)
+
+		r = httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(cookies[0])
+		values, err := i.SessionStore.Load(r)
+		suite.Nil(err)
+		suite.Equal(float64(n), values["count"])
+	}
+}
+
+func (suite *InertiaSessionTestSuite) TestSessionFromContextWithoutStore() {
+	i := inertia.New("", "", "")
+
+	called := false
+	i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		suite.Nil(inertia.SessionFromContext(r.Context()))
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	suite.True(called)
+}
+
+func (suite *InertiaSessionTestSuite) TestFlash() {
+	i := inertia.New("", "", "")
+	i.SessionStore = suite.cookieStore("secret")
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			suite.Nil(i.Flash(w, r, "success", "Saved!"))
+			suite.Nil(i.FlashErrors(w, r, inertia.ValidationErrors{"name": "The name field is required."}))
+			http.Redirect(w, r, "/users", http.StatusSeeOther)
+
+			return
+		}
+
+		suite.Nil(i.Render(w, r, "Users", nil))
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/users", nil)
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusSeeOther, w.Code)
+
+	w, page := suite.visit(handler, w.Result().Cookies())
+	suite.Equal(map[string]any{"success": "Saved!"}, page.Props["flash"])
+	suite.Equal(map[string]any{"name": "The name field is required."}, page.Props["errors"])
+
+	// Flashed data is cleared once rendered.
+	_, page = suite.visit(handler, w.Result().Cookies())
+	suite.Equal(map[string]any{}, page.Props["flash"])
+	suite.Equal(map[string]any{}, page.Props["errors"])
+}
+
+func (suite *InertiaSessionTestSuite) TestFlashSurvivesVersionConflict() {
+	i := inertia.New("", "./index_test.html", "2")
+	i.SessionStore = suite.cookieStore("secret")
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			suite.Nil(i.Flash(w, r, "success", "Saved!"))
+			suite.Nil(i.FlashErrors(w, r, inertia.ValidationErrors{"name": "The name field is required."}))
+			http.Redirect(w, r, "/users", http.StatusSeeOther)
+
+			return
+		}
+
+		suite.Nil(i.Render(w, r, "Users", nil))
+	}))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/users", nil)
+	r.Header.Set("X-Inertia", "true")
+	r.Header.Set("X-Inertia-Version", "1")
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusSeeOther, w.Code)
+	cookies := w.Result().Cookies()
+
+	// The redirected visit hits the version conflict.
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/users", nil)
+	r.Header.Set("X-Inertia", "true")
+	r.Header.Set("X-Inertia-Version", "1")
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusConflict, w.Code)
+	// The session is untouched, so the browser keeps sending the original cookie.
+	suite.Empty(w.Result().Cookies())
+
+	// The full page reload still shows the flashed data.
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/users", nil)
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusOK, w.Code)
+
+	body := html.UnescapeString(w.Body.String())
+	suite.Contains(body, `"flash":{"success":"Saved!"}`)
+	suite.Contains(body, `"errors":{"name":"The name field is required."}`)
+}
+
+func (suite *InertiaSessionTestSuite) TestFlashCustomKey() {
+	i := inertia.New("", "", "")
+	i.FlashKey = "messages"
+	i.SessionStore = inertia.NewMemorySessionStore()
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Nil(i.Flash(w, r, "success", "Saved!"))
+		suite.Nil(i.Render(w, r, "Users", nil))
+	}))
+
+	_, page := suite.visit(handler, nil)
+	suite.Equal(map[string]any{"success": "Saved!"}, page.Props["messages"])
+	suite.NotContains(page.Props, "flash")
+}
+
+func (suite *InertiaSessionTestSuite) TestFlashWithoutSession() {
+	i := inertia.New("", "", "")
+	w, r := mockRequest("POST", "/users", Headers{})
+
+	suite.ErrorIs(i.Flash(w, r, "success", "Saved!"), inertia.ErrNoSession)
+	suite.ErrorIs(i.FlashErrors(w, r, inertia.ValidationErrors{}), inertia.ErrNoSession)
+}
+
+// visit makes an Inertia request with the given cookies and decodes the rendered page.
+func (suite *InertiaSessionTestSuite) visit(handler http.Handler, cookies []*http.Cookie) (*httptest.ResponseRecorder, inertia.Page) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/users", nil)
+	r.Header.Set("X-Inertia", "true")
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+
+	handler.ServeHTTP(w, r)
+
+	var page inertia.Page
+	suite.Nil(json.Unmarshal(w.Body.Bytes(), &page))
+
+	return w, page
+}
+
+func (suite *InertiaSessionTestSuite) TestSessionSaveError() {
+	i := inertia.New("", "", "")
+	i.SessionStore = suite.cookieStore("secret")
+
+	var saveErr error
+	i.OnSessionError = func(r *http.Request, err error) {
+		saveErr = err
+	}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inertia.SessionFromContext(r.Context()).Put("foo", strings.Repeat("a", 5000))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	suite.ErrorIs(saveErr, inertia.ErrSessionTooLarge)
+}
+
+func (suite *InertiaSessionTestSuite) TestSessionLoadError() {
+	i := inertia.New("", "", "")
+	store := suite.cookieStore("secret")
+	i.SessionStore = store
+
+	var loadErr error
+	i.OnSessionError = func(r *http.Request, err error) {
+		loadErr = err
+	}
+
+	var session *inertia.Session
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session = inertia.SessionFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: store.Name, Value: "tampered"})
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	suite.ErrorIs(loadErr, inertia.ErrInvalidSession)
+	suite.Empty(session.Values())
+}
+
+func (suite *InertiaSessionTestSuite) cookieStore(secrets ...string) *inertia.CookieSessionStore {
+	keys := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		keys = append(keys, []byte(secret))
+	}
+
+	store, err := inertia.NewCookieSessionStore(keys...)
+	suite.Require().Nil(err)
+
+	return store
+}
+
+func TestInertiaSessionSuite(t *testing.T) {
+	suite.Run(t, new(InertiaSessionTestSuite))
+}
@@ -4,7 +4,12 @@ import (
 	"github.com/humweb/inertia-go"
 	"github.com/stretchr/testify/suite"
 	"html/template"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 type InertiaTemplateTestSuite struct {
@@ -42,6 +47,56 @@ func (suite *InertiaTemplateTestSuite) TestTemplateRawErr() {
 
 }
 
+func (suite *InertiaTemplateTestSuite) TestRootTemplateCache() {
+	path := filepath.Join(suite.T().TempDir(), "app.html")
+	suite.Nil(os.WriteFile(path, []byte(`v1 {{ greet }}`), 0o600))
+
+	i := inertia.New("", path, "")
+	i.ShareFunc("greet", func() string { return "hello" })
+
+	suite.Equal("v1 hello", suite.render(i))
+
+	// The parsed template is reused until ShareFunc registers a function.
+	suite.Nil(os.WriteFile(path, []byte(`v2 {{ greet }}`), 0o600))
+	suite.Equal("v1 hello", suite.render(i))
+
+	i.ShareFunc("greet", func() string { return "hi" })
+	suite.Equal("v2 hi", suite.render(i))
+}
+
+func (suite *InertiaTemplateTestSuite) TestRootTemplateDevMode() {
+	path := filepath.Join(suite.T().TempDir(), "app.html")
+	suite.Nil(os.WriteFile(path, []byte(`v1`), 0o600))
+
+	i := inertia.New("", path, "")
+	i.DevMode = true
+
+	suite.Equal("v1", suite.render(i))
+
+	suite.Nil(os.WriteFile(path, []byte(`v2`), 0o600))
+	later := time.Now().Add(time.Minute)
+	suite.Nil(os.Chtimes(path, later, later))
+	suite.Equal("v2", suite.render(i))
+
+	fsys := fstest.MapFS{"app.html": {Data: []byte(`fs v1`)}}
+	i = inertia.NewWithFS("", "app.html", "", fsys)
+	i.DevMode = true
+
+	suite.Equal("fs v1", suite.render(i))
+
+	fsys["app.html"] = &fstest.MapFile{Data: []byte(`fs v2`)}
+	suite.Equal("fs v2", suite.render(i))
+}
+
+func (suite *InertiaTemplateTestSuite) render(i *inertia.Inertia) string {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+
+	suite.Nil(i.Render(w, r, "Home", nil))
+
+	return w.Body.String()
+}
+
 func TestInertiaTemplateSuite(t *testing.T) {
 	suite.Run(t, new(InertiaTemplateTestSuite))
 }
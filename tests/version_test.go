@@ -0,0 +1,238 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/humweb/inertia-go"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type InertiaVersionTestSuite struct {
+	suite.Suite
+}
+
+func (suite *InertiaVersionTestSuite) TestStaticVersion() {
+	version, err := inertia.New("", "", "1").Version()
+	suite.Nil(err)
+	suite.Equal("1", version)
+}
+
+func (suite *InertiaVersionTestSuite) TestManifestVersion() {
+	fsys := fstest.MapFS{"build/manifest.json": {Data: []byte(`{"app.js":{}}`)}}
+	provider := inertia.ManifestVersion(fsys, "build/manifest.json")
+
+	version, err := provider()
+	suite.Nil(err)
+	suite.Len(version, 32)
+
+	// The manifest is only hashed once.
+	fsys["build/manifest.json"] = &fstest.MapFile{Data: []byte(`{}`)}
+	again, err := provider()
+	suite.Nil(err)
+	suite.Equal(version, again)
+
+	_, err = inertia.ManifestVersion(fsys, "missing.json")()
+	suite.Error(err)
+}
+
+func (suite *InertiaVersionTestSuite) TestFileVersion() {
+	path := filepath.Join(suite.T().TempDir(), "manifest.json")
+	suite.Nil(os.WriteFile(path, []byte(`{"app.js":{}}`), 0o600))
+
+	provider := inertia.FileVersion(path)
+
+	version, err := provider()
+	suite.Nil(err)
+
+	suite.Nil(os.WriteFile(path, []byte(`{"app-2.js":{}}`), 0o600))
+	later := time.Now().Add(time.Minute)
+	suite.Nil(os.Chtimes(path, later, later))
+
+	updated, err := provider()
+	suite.Nil(err)
+	suite.NotEqual(version, updated)
+
+	suite.Nil(os.Remove(path))
+	_, err = provider()
+	suite.Error(err)
+}
+
+func (suite *InertiaVersionTestSuite) TestBuildInfoVersion() {
+	// Test binaries carry no VCS stamp.
+	_, err := inertia.BuildInfoVersion()()
+	suite.ErrorIs(err, inertia.ErrNoBuildRevision)
+}
+
+func (suite *InertiaVersionTestSuite) TestVersionProvider() {
+	i := inertia.New("", "", "1")
+	i.VersionProvider = func() (string, error) {
+		return "2", nil
+	}
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Inertia-Version": "1"})
+	i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(w, r)
+	suite.Equal(http.StatusConflict, w.Code)
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Inertia-Version": "2"})
+	i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Nil(i.Render(w, r, "Users", nil))
+	})).ServeHTTP(w, r)
+	suite.Equal(http.StatusOK, w.Code)
+
+	var page inertia.Page
+	suite.Nil(json.Unmarshal(w.Body.Bytes(), &page))
+	suite.Equal("2", page.Version)
+}
+
+func (suite *InertiaVersionTestSuite) TestVersionProviderError() {
+	i := inertia.New("", "", "1")
+	i.VersionProvider = func() (string, error) {
+		return "", errors.New("no manifest")
+	}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Equal("Internal Server Error\n", w.Body.String())
+
+	// Debug shows the provider error, like any other server error.
+	i.Debug = true
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Equal("no manifest\n", w.Body.String())
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true"})
+	suite.Error(i.Render(w, r, "Users", nil))
+}
+
+func (suite *InertiaVersionTestSuite) TestSetVersion() {
+	i := inertia.New("", "", "1")
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Inertia-Version": "1"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusOK, w.Code)
+
+	i.SetVersion("2")
+
+	version, err := i.Version()
+	suite.Nil(err)
+	suite.Equal("2", version)
+
+	w, r = mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Inertia-Version": "1"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusConflict, w.Code)
+}
+
+func (suite *InertiaVersionTestSuite) TestSetReleaseInFlight() {
+	dir := suite.T().TempDir()
+	suite.Nil(os.WriteFile(filepath.Join(dir, "v1.html"), []byte(`v1 {{ .page.Version }}`), 0o600))
+	suite.Nil(os.WriteFile(filepath.Join(dir, "v2.html"), []byte(`v2 {{ .page.Version }}`), 0o600))
+
+	i := inertia.New("", filepath.Join(dir, "v1.html"), "1")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("wait") {
+			close(started)
+			<-release
+		}
+
+		suite.Nil(i.Render(w, r, "Users", nil))
+	}))
+
+	inFlight := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(inFlight, httptest.NewRequest("GET", "/users?wait", nil))
+	}()
+
+	<-started
+	i.SetRelease(inertia.Release{Version: "2", RootTemplate: filepath.Join(dir, "v2.html")})
+	close(release)
+	<-done
+
+	// The in-flight request finishes with the release it started with.
+	suite.Equal("v1 1", inFlight.Body.String())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/users", nil))
+	suite.Equal("v2 2", w.Body.String())
+}
+
+func (suite *InertiaVersionTestSuite) TestSetReleaseVite() {
+	fsys := fstest.MapFS{
+		"app.html": {Data: []byte(`{{ vite "app.js" }}`)},
+		"v1.json":  {Data: []byte(`{"app.js":{"file":"app-1.js","isEntry":true}}`)},
+		"v2.json":  {Data: []byte(`{"app.js":{"file":"app-2.js","isEntry":true}}`)},
+	}
+
+	i := inertia.NewWithFS("", "app.html", "1", fsys)
+	i.EnableVite("v1.json", "/build")
+
+	w := httptest.NewRecorder()
+	suite.Nil(i.Render(w, httptest.NewRequest("GET", "/", nil), "Home", nil))
+	suite.Contains(w.Body.String(), "/build/app-1.js")
+
+	i.SetRelease(inertia.Release{Version: "2", Vite: inertia.NewVite(fsys, "v2.json", "/build")})
+
+	w = httptest.NewRecorder()
+	suite.Nil(i.Render(w, httptest.NewRequest("GET", "/", nil), "Home", nil))
+	suite.Contains(w.Body.String(), "/build/app-2.js")
+}
+
+func (suite *InertiaVersionTestSuite) TestSetReleaseKeepsVersion() {
+	i := inertia.New("", "", "1")
+	i.SetRelease(inertia.Release{RootTemplate: "./index_test.html"})
+
+	version, err := i.Version()
+	suite.Nil(err)
+	suite.Equal("1", version)
+}
+
+func (suite *InertiaVersionTestSuite) TestVersionProviderPinned() {
+	i := inertia.New("", "", "")
+
+	var calls int
+	i.VersionProvider = func() (string, error) {
+		calls++
+
+		return strconv.Itoa(calls), nil
+	}
+
+	handler := i.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Nil(i.Render(w, r, "Users", nil))
+	}))
+
+	// The version checked by the middleware is the one rendered.
+	w, r := mockRequest("GET", "/users", Headers{"X-Inertia": "true", "X-Inertia-Version": "1"})
+	handler.ServeHTTP(w, r)
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Contains(w.Body.String(), `"version":"1"`)
+	suite.Equal(1, calls)
+
+	// A version set at runtime takes precedence over the provider.
+	i.SetVersion("deploy-2")
+
+	version, err := i.Version()
+	suite.Nil(err)
+	suite.Equal("deploy-2", version)
+	suite.Equal(1, calls)
+}
+
+func TestInertiaVersionSuite(t *testing.T) {
+	suite.Run(t, new(InertiaVersionTestSuite))
+}
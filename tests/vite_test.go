@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"github.com/humweb/inertia-go"
+	"github.com/stretchr/testify/suite"
+	"html/template"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+const viteManifest = `{
+  "resources/js/app.ts": {
+    "file": "assets/app-4ed993c7.js",
+    "src": "resources/js/app.ts",
+    "isEntry": true,
+    "imports": ["_shared-B7PI925R.js"],
+    "css": ["assets/app-5b2f1a3c.css"]
+  },
+  "resources/js/admin.ts": {
+    "file": "assets/admin-1a2b3c4d.js",
+    "src": "resources/js/admin.ts",
+    "isEntry": true,
+    "imports": ["_shared-B7PI925R.js"]
+  },
+  "_shared-B7PI925R.js": {
+    "file": "assets/shared-B7PI925R.js",
+    "css": ["assets/shared-ChJ_j-JJ.css"]
+  },
+  "resources/css/print.css": {
+    "file": "assets/print-0c3d2e1f.css",
+    "src": "resources/css/print.css",
+    "isEntry": true
+  },
+  "resources/images/logo.png": {
+    "file": "assets/logo-d015fb1d.png",
+    "src": "resources/images/logo.png"
+  }
+}`
+
+type InertiaViteTestSuite struct {
+	suite.Suite
+}
+
+func (suite *InertiaViteTestSuite) vite() *inertia.Vite {
+	fsys := fstest.MapFS{"build/manifest.json": {Data: []byte(viteManifest)}}
+
+	return inertia.NewVite(fsys, "build/manifest.json", "/build/")
+}
+
+func (suite *InertiaViteTestSuite) TestTags() {
+	tags, err := suite.vite().Tags("resources/js/app.ts")
+	suite.Nil(err)
+	suite.Equal(template.HTML(
+		`<link rel="stylesheet" href="/build/assets/shared-ChJ_j-JJ.css">`+"\n"+
+			`<link rel="stylesheet" href="/build/assets/app-5b2f1a3c.css">`+"\n"+
+			`<link rel="modulepreload" href="/build/assets/shared-B7PI925R.js">`+"\n"+
+			`<script type="module" src="/build/assets/app-4ed993c7.js"></script>`,
+	), tags)
+}
+
+func (suite *InertiaViteTestSuite) TestTagsMultipleEntries() {
+	tags, err := suite.vite().Tags("resources/js/app.ts", "resources/js/admin.ts", "resources/css/print.css")
+	suite.Nil(err)
+	suite.Equal(template.HTML(
+		`<link rel="stylesheet" href="/build/assets/shared-ChJ_j-JJ.css">`+"\n"+
+			`<link rel="stylesheet" href="/build/assets/app-5b2f1a3c.css">`+"\n"+
+			`<link rel="stylesheet" href="/build/assets/print-0c3d2e1f.css">`+"\n"+
+			`<link rel="modulepreload" href="/build/assets/shared-B7PI925R.js">`+"\n"+
+			`<script type="module" src="/build/assets/app-4ed993c7.js"></script>`+"\n"+
+			`<script type="module" src="/build/assets/admin-1a2b3c4d.js"></script>`,
+	), tags)
+}
+
+func (suite *InertiaViteTestSuite) TestAsset() {
+	url, err := suite.vite().Asset("resources/images/logo.png")
+	suite.Nil(err)
+	suite.Equal("/build/assets/logo-d015fb1d.png", url)
+
+	_, err = suite.vite().Asset("missing.png")
+	suite.ErrorIs(err, inertia.ErrViteEntryNotFound)
+
+	_, err = suite.vite().Tags("missing.ts")
+	suite.ErrorIs(err, inertia.ErrViteEntryNotFound)
+}
+
+func (suite *InertiaViteTestSuite) TestManifestErrors() {
+	_, err := inertia.NewVite(fstest.MapFS{}, "manifest.json", "/build").Tags("resources/js/app.ts")
+	suite.Error(err)
+
+	_, err = inertia.NewVite(fstest.MapFS{"manifest.json": {Data: []byte("{")}}, "manifest.json", "/build").Tags("resources/js/app.ts")
+	suite.Error(err)
+
+	_, err = inertia.NewVite(nil, "./missing/manifest.json", "/build").Asset("resources/images/logo.png")
+	suite.Error(err)
+}
+
+func (suite *InertiaViteTestSuite) TestEnableVite() {
+	fsys := fstest.MapFS{
+		"app.html":            {Data: []byte(`{{ vite "resources/js/admin.ts" }}<img src="{{ viteAsset "resources/images/logo.png" }}">`)},
+		"build/manifest.json": {Data: []byte(viteManifest)},
+	}
+
+	i := inertia.NewWithFS("", "app.html", "", fsys)
+	vite := i.EnableVite("build/manifest.json", "/build")
+	suite.Same(vite, i.Vite())
+	suite.False(vite.IsDev())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	suite.Nil(i.Render(w, r, "Home", nil))
+
+	suite.Equal(
+		`<link rel="stylesheet" href="/build/assets/shared-ChJ_j-JJ.css">`+"\n"+
+			`<link rel="modulepreload" href="/build/assets/shared-B7PI925R.js">`+"\n"+
+			`<script type="module" src="/build/assets/admin-1a2b3c4d.js"></script>`+
+			`<img src="/build/assets/logo-d015fb1d.png">`,
+		w.Body.String(),
+	)
+}
+
+func (suite *InertiaViteTestSuite) TestEscaping() {
+	fsys := fstest.MapFS{
+		"app.html": {Data: []byte(`{{ vite "resources/js/app.ts" }}<img src="{{ viteAsset "resources/images/a&b.png" }}">`)},
+		"build/manifest.json": {Data: []byte(`{
+			"resources/js/app.ts": {"file": "assets/app.js?v=1&x=2", "isEntry": true},
+			"resources/images/a&b.png": {"file": "assets/a&b.png"}
+		}`)},
+	}
+
+	i := inertia.NewWithFS("", "app.html", "", fsys)
+	vite := i.EnableVite("build/manifest.json", "/build")
+
+	url, err := vite.Asset("resources/images/a&b.png")
+	suite.Nil(err)
+	suite.Equal("/build/assets/a&b.png", url)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	suite.Nil(i.Render(w, r, "Home", nil))
+
+	suite.Equal(
+		`<script type="module" src="/build/assets/app.js?v=1&amp;x=2"></script>`+
+			`<img src="/build/assets/a&amp;b.png">`,
+		w.Body.String(),
+	)
+}
+
+func (suite *InertiaViteTestSuite) TestDevServer() {
+	vite := inertia.NewVite(fstest.MapFS{}, "manifest.json", "/build")
+	vite.DevServerURL = "http://localhost:5173/"
+	suite.True(vite.IsDev())
+
+	tags, err := vite.Tags("resources/js/app.ts", "resources/css/app.css")
+	suite.Nil(err)
+	suite.Equal(template.HTML(
+		`<script type="module" src="http://localhost:5173/@vite/client"></script>`+"\n"+
+			`<script type="module" src="http://localhost:5173/resources/js/app.ts"></script>`+"\n"+
+			`<link rel="stylesheet" href="http://localhost:5173/resources/css/app.css">`,
+	), tags)
+
+	url, err := vite.Asset("resources/images/logo.png")
+	suite.Nil(err)
+	suite.Equal("http://localhost:5173/resources/images/logo.png", url)
+
+	vite.ReactRefresh = true
+	tags, err = vite.Tags("resources/js/app.tsx")
+	suite.Nil(err)
+	suite.Contains(string(tags), `import RefreshRuntime from 'http://localhost:5173/@react-refresh'`)
+	suite.Contains(string(tags), `window.__vite_plugin_react_preamble_installed__ = true`)
+}
+
+func (suite *InertiaViteTestSuite) TestHotFile() {
+	hot := filepath.Join(suite.T().TempDir(), "hot")
+
+	vite := suite.vite()
+	vite.HotFile = hot
+	suite.False(vite.IsDev())
+
+	tags, err := vite.Tags("resources/js/admin.ts")
+	suite.Nil(err)
+	suite.Contains(string(tags), "/build/assets/admin-1a2b3c4d.js")
+
+	suite.Nil(os.WriteFile(hot, []byte("http://[::1]:5173\n"), 0o600))
+	suite.True(vite.IsDev())
+
+	tags, err = vite.Tags("resources/js/admin.ts")
+	suite.Nil(err)
+	suite.Equal(template.HTML(
+		`<script type="module" src="http://[::1]:5173/@vite/client"></script>`+"\n"+
+			`<script type="module" src="http://[::1]:5173/resources/js/admin.ts"></script>`,
+	), tags)
+
+	suite.Nil(os.Remove(hot))
+	suite.False(vite.IsDev())
+}
+
+func (suite *InertiaViteTestSuite) TestManifestRebuild() {
+	manifest := filepath.Join(suite.T().TempDir(), "manifest.json")
+	suite.Nil(os.WriteFile(manifest, []byte(viteManifest), 0o600))
+
+	vite := inertia.NewVite(nil, manifest, "/build")
+
+	url, err := vite.Asset("resources/images/logo.png")
+	suite.Nil(err)
+	suite.Equal("/build/assets/logo-d015fb1d.png", url)
+
+	rebuilt := strings.ReplaceAll(viteManifest, "logo-d015fb1d", "logo-7c1e0f2a")
+	suite.Nil(os.WriteFile(manifest, []byte(rebuilt), 0o600))
+
+	// Make sure the rebuild is seen on file systems with a coarse modification time.
+	later := time.Now().Add(time.Minute)
+	suite.Nil(os.Chtimes(manifest, later, later))
+
+	url, err = vite.Asset("resources/images/logo.png")
+	suite.Nil(err)
+	suite.Equal("/build/assets/logo-7c1e0f2a.png", url)
+}
+
+func TestInertiaViteSuite(t *testing.T) {
+	suite.Run(t, new(InertiaViteTestSuite))
+}
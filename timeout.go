@@ -0,0 +1,115 @@
+package inertia
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// FailurePolicy decides what happens to a TimeoutProp that failed or timed out.
+type FailurePolicy int
+
+const (
+	// FailPage fails the whole page with the prop error.
+	FailPage FailurePolicy = iota
+	// OmitProp leaves the prop out of the page.
+	OmitProp
+	// UseFallback sends the fallback value instead.
+	UseFallback
+)
+
+// TimeoutProp is a property value whose resolution is abandoned after the timeout,
+// so one slow resolver does not hold the whole page. Failures are handled following the policy,
+// degraded props are reported to OnPropDegraded.
+type TimeoutProp struct {
+	Value    any
+	Timeout  time.Duration
+	Policy   FailurePolicy
+	Fallback any
+}
+
+// Timeout creates a TimeoutProp failing the page when the value, a closure or resolver,
+// fails or is not resolved within the timeout. Deferred and lazy props keep being
+// left out of the initial page load when wrapped, always and merge props keep their
+// behaviour. A TimeoutProp may itself be deferred with Defer(Timeout(f, d)).
+func Timeout(value any, timeout time.Duration) TimeoutProp {
+	return TimeoutProp{Value: value, Timeout: timeout, Policy: FailPage}
+}
+
+// OrOmit leaves the prop out of the page when it fails or times out.
+func (p TimeoutProp) OrOmit() TimeoutProp {
+	p.Policy = OmitProp
+
+	return p
+}
+
+// OrFallback sends the fallback value when the prop fails or times out.
+func (p TimeoutProp) OrFallback(fallback any) TimeoutProp {
+	p.Policy = UseFallback
+	p.Fallback = fallback
+
+	return p
+}
+
+// omitted marks a resolved prop to be left out of the page.
+type omitted struct{}
+
+type timeoutResult struct {
+	val      any
+	err      error
+	panicked any
+}
+
+// resolveTimeoutProp resolves the prop value, waiting at most the prop timeout.
+func (i *Inertia) resolveTimeoutProp(r *http.Request, key string, prop TimeoutProp) (any, error) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if prop.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(r.Context(), prop.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(r.Context())
+	}
+	defer cancel()
+
+	// Buffered, so an abandoned resolver can still finish.
+	done := make(chan timeoutResult, 1)
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				recordPanicStack(r)
+				done <- timeoutResult{panicked: p}
+			}
+		}()
+
+		val, err := i.resolvePropVal(r.WithContext(ctx), key, prop.Value)
+		done <- timeoutResult{val: val, err: err}
+	}()
+
+	var res timeoutResult
+
+	select {
+	case res = <-done:
+		if res.panicked != nil {
+			panic(res.panicked)
+		}
+	case <-ctx.Done():
+		res.err = ctx.Err()
+	}
+
+	// Nothing to degrade when the whole request is being cancelled.
+	if res.err == nil || prop.Policy == FailPage || r.Context().Err() != nil {
+		return res.val, res.err
+	}
+
+	if i.OnPropDegraded != nil {
+		i.OnPropDegraded(r, &PropError{Key: key, Err: res.err})
+	}
+
+	if prop.Policy == OmitProp {
+		return omitted{}, nil
+	}
+
+	return prop.Fallback, nil
+}
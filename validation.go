@@ -0,0 +1,54 @@
+package inertia
+
+import (
+	"context"
+	"net/http"
+)
+
+// ValidationErrors maps form field names to their error messages.
+type ValidationErrors map[string]string
+
+// WithErrors appends validation errors to the passed context.Context.
+func (i *Inertia) WithErrors(ctx context.Context, errors ValidationErrors) context.Context {
+	merged := make(ValidationErrors)
+
+	if ctxErrors, ok := ctx.Value(ContextKeyErrors).(ValidationErrors); ok {
+		for key, val := range ctxErrors {
+			merged[key] = val
+		}
+	}
+
+	for key, val := range errors {
+		merged[key] = val
+	}
+
+	return context.WithValue(ctx, ContextKeyErrors, merged)
+}
+
+// WithError appends a single validation error to the passed context.Context.
+func (i *Inertia) WithError(ctx context.Context, key, message string) context.Context {
+	return i.WithErrors(ctx, ValidationErrors{key: message})
+}
+
+// resolveErrors builds the `errors` prop value from the flashed and context errors, scoped under the error bag
+// when the client sends the X-Inertia-Error-Bag header.
+func (i *Inertia) resolveErrors(r *http.Request) (any, error) {
+	errors := pullFlashErrors(r)
+
+	if ctxErrors := r.Context().Value(ContextKeyErrors); ctxErrors != nil {
+		ctxErrors, ok := ctxErrors.(ValidationErrors)
+		if !ok {
+			return nil, ErrInvalidContextErrors
+		}
+
+		for key, val := range ctxErrors {
+			errors[key] = val
+		}
+	}
+
+	if bag := r.Header.Get(Headers.ErrorBag); bag != "" && len(errors) > 0 {
+		return Props{bag: errors}, nil
+	}
+
+	return errors, nil
+}
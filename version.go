@@ -0,0 +1,118 @@
+package inertia
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// VersionProvider returns the current asset version. It is evaluated once for
+// every request, so it should be cheap.
+//
+// https://inertiajs.com/asset-versioning
+type VersionProvider func() (string, error)
+
+// Version returns the current asset version, from the VersionProvider when set,
+// unless a version was set with SetVersion or SetRelease.
+func (i *Inertia) Version() (string, error) {
+	return i.releaseVersion(i.release.Load())
+}
+
+// ManifestVersion hashes a build manifest, e.g. the Vite manifest.json or the Mix
+// mix-manifest.json, read once from fsys.
+func ManifestVersion(fsys fs.FS, path string) VersionProvider {
+	var once sync.Once
+	var version string
+	var err error
+
+	return func() (string, error) {
+		once.Do(func() {
+			var content []byte
+			if content, err = fs.ReadFile(fsys, path); err == nil {
+				version = hashVersion(content)
+			}
+		})
+
+		return version, err
+	}
+}
+
+// FileVersion hashes the file at path on disk, hashing it again whenever its
+// modification time or size changes, so a new build is picked up without a restart.
+func FileVersion(path string) VersionProvider {
+	var mu sync.Mutex
+	var version string
+	var modTime time.Time
+	var size int64 = -1
+
+	return func() (string, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if info.ModTime().Equal(modTime) && info.Size() == size {
+			return version, nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+
+		version, modTime, size = hashVersion(content), info.ModTime(), info.Size()
+
+		return version, nil
+	}
+}
+
+// BuildInfoVersion uses the VCS revision the binary was built from, with a
+// "-dirty" suffix for modified working trees. It requires building with VCS stamping.
+func BuildInfoVersion() VersionProvider {
+	version, err := buildInfoRevision()
+
+	return func() (string, error) {
+		return version, err
+	}
+}
+
+func buildInfoRevision() (string, error) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", ErrNoBuildRevision
+	}
+
+	revision, modified := "", false
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+
+	if revision == "" {
+		return "", ErrNoBuildRevision
+	}
+
+	if modified {
+		revision += "-dirty"
+	}
+
+	return revision, nil
+}
+
+func hashVersion(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:16])
+}
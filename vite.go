@@ -0,0 +1,290 @@
+package inertia
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ViteChunk is an entry of the Vite build manifest.
+//
+// https://vitejs.dev/guide/backend-integration.html
+type ViteChunk struct {
+	File    string   `json:"file"`
+	Src     string   `json:"src"`
+	IsEntry bool     `json:"isEntry"`
+	Imports []string `json:"imports"`
+	CSS     []string `json:"css"`
+}
+
+// Vite renders the script, stylesheet and module preload tags of a Vite build,
+// or of the Vite dev server while it is running.
+type Vite struct {
+	// BuildURL is the public URL the build directory is served from, e.g. "/build".
+	BuildURL string
+
+	// HotFile is the file the Vite dev server writes its URL to while running, e.g. "public/hot".
+	// The tags point at the dev server while the file exists.
+	HotFile string
+
+	// DevServerURL points the tags at the Vite dev server, e.g. "http://localhost:5173".
+	DevServerURL string
+
+	// ReactRefresh adds the React refresh preamble in dev mode, required by @vitejs/plugin-react.
+	ReactRefresh bool
+
+	fsys         fs.FS
+	manifestPath string
+
+	mu       sync.Mutex
+	manifest map[string]ViteChunk
+	modTime  time.Time
+	size     int64
+}
+
+// NewVite creates a Vite reading the manifest at manifestPath from fsys, or from disk when fsys is nil.
+// The manifest is read on first use, it is not needed in dev mode, and read again
+// when its modification time or size changes, e.g. after `vite build --watch`.
+func NewVite(fsys fs.FS, manifestPath, buildURL string) *Vite {
+	return &Vite{BuildURL: buildURL, fsys: fsys, manifestPath: manifestPath}
+}
+
+// EnableVite shares the `vite` and `viteAsset` functions with the root template.
+// The Vite manifest is read from the template FS, or from disk.
+//
+//	{{ vite "resources/js/app.ts" }}
+//	<img src="{{ viteAsset "resources/images/logo.png" }}">
+func (i *Inertia) EnableVite(manifestPath, buildURL string) *Vite {
+	vite := NewVite(i.templateFS, manifestPath, buildURL)
+
+	i.shareFuncs(template.FuncMap{"vite": vite.Tags, "viteAsset": vite.Asset})
+	i.updateRelease(func(rel *release) {
+		rel.vite = vite
+	})
+
+	return vite
+}
+
+// IsDev reports whether the tags point at the Vite dev server.
+func (v *Vite) IsDev() bool {
+	_, ok := v.devServerURL()
+
+	return ok
+}
+
+// Tags returns the tags loading the given entries: stylesheets, module preloads
+// for the imported chunks and the entry scripts.
+func (v *Vite) Tags(entries ...string) (template.HTML, error) {
+	if url, ok := v.devServerURL(); ok {
+		return v.devTags(url, entries), nil
+	}
+
+	manifest, err := v.loadManifest()
+	if err != nil {
+		return "", err
+	}
+
+	var styles, preloads, scripts []string
+	seen, entered := make(map[string]bool), make(map[string]bool)
+
+	for _, entry := range entries {
+		chunk, ok := manifest[entry]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrViteEntryNotFound, entry)
+		}
+
+		walkImports(manifest, chunk, seen, func(imported ViteChunk) {
+			preloads = append(preloads, `<link rel="modulepreload" href="`+template.HTMLEscapeString(v.url(imported.File))+`">`)
+			styles = append(styles, v.styleTags(imported.CSS, seen)...)
+		})
+
+		styles = append(styles, v.styleTags(chunk.CSS, seen)...)
+
+		if entered[chunk.File] {
+			continue
+		}
+		entered[chunk.File] = true
+
+		if isCSS(chunk.File) {
+			styles = append(styles, styleTag(v.url(chunk.File)))
+		} else {
+			scripts = append(scripts, scriptTag(v.url(chunk.File)))
+		}
+	}
+
+	tags := append(append(styles, preloads...), scripts...)
+
+	return template.HTML(strings.Join(tags, "\n")), nil
+}
+
+// Asset returns the public URL of a file processed by Vite. The URL is not escaped,
+// html/template escapes it for the context it is used in.
+func (v *Vite) Asset(src string) (string, error) {
+	if url, ok := v.devServerURL(); ok {
+		return devURL(url, src), nil
+	}
+
+	manifest, err := v.loadManifest()
+	if err != nil {
+		return "", err
+	}
+
+	chunk, ok := manifest[src]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrViteEntryNotFound, src)
+	}
+
+	return v.url(chunk.File), nil
+}
+
+// devTags returns the Vite client and the source entries served by the dev server.
+func (v *Vite) devTags(url string, entries []string) template.HTML {
+	tags := make([]string, 0, len(entries)+2)
+
+	if v.ReactRefresh {
+		tags = append(tags, `<script type="module">
+import RefreshRuntime from '`+template.JSEscapeString(devURL(url, "@react-refresh"))+`'
+RefreshRuntime.injectIntoGlobalHook(window)
+window.$RefreshReg$ = () => {}
+window.$RefreshSig$ = () => (type) => type
+window.__vite_plugin_react_preamble_installed__ = true
+</script>`)
+	}
+
+	tags = append(tags, scriptTag(devURL(url, "@vite/client")))
+
+	for _, entry := range entries {
+		if isCSS(entry) {
+			tags = append(tags, styleTag(devURL(url, entry)))
+		} else {
+			tags = append(tags, scriptTag(devURL(url, entry)))
+		}
+	}
+
+	return template.HTML(strings.Join(tags, "\n"))
+}
+
+// devServerURL returns the configured dev server URL, else the URL written to the hot file.
+func (v *Vite) devServerURL() (string, bool) {
+	if v.DevServerURL != "" {
+		return v.DevServerURL, true
+	}
+
+	if v.HotFile == "" {
+		return "", false
+	}
+
+	content, err := os.ReadFile(v.HotFile)
+	if err != nil {
+		return "", false
+	}
+
+	url := strings.TrimSpace(string(content))
+
+	return url, url != ""
+}
+
+// loadManifest reads the build manifest, again only when the file changed since the last read.
+func (v *Vite) loadManifest() (map[string]ViteChunk, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var info fs.FileInfo
+	var err error
+
+	if v.fsys != nil {
+		info, err = fs.Stat(v.fsys, v.manifestPath)
+	} else {
+		info, err = os.Stat(v.manifestPath)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read vite manifest: %w", err)
+	}
+
+	if v.manifest != nil && info.ModTime().Equal(v.modTime) && info.Size() == v.size {
+		return v.manifest, nil
+	}
+
+	var content []byte
+
+	if v.fsys != nil {
+		content, err = fs.ReadFile(v.fsys, v.manifestPath)
+	} else {
+		content, err = os.ReadFile(v.manifestPath)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("read vite manifest: %w", err)
+	}
+
+	manifest := make(map[string]ViteChunk)
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("decode vite manifest: %w", err)
+	}
+
+	v.manifest, v.modTime, v.size = manifest, info.ModTime(), info.Size()
+
+	return manifest, nil
+}
+
+// walkImports calls fn for every chunk statically imported by chunk, depth first, once.
+func walkImports(manifest map[string]ViteChunk, chunk ViteChunk, seen map[string]bool, fn func(ViteChunk)) {
+	for _, name := range chunk.Imports {
+		imported, ok := manifest[name]
+		if !ok || seen[imported.File] {
+			continue
+		}
+		seen[imported.File] = true
+
+		walkImports(manifest, imported, seen, fn)
+		fn(imported)
+	}
+}
+
+func (v *Vite) styleTags(files []string, seen map[string]bool) []string {
+	tags := make([]string, 0, len(files))
+
+	for _, file := range files {
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+
+		tags = append(tags, styleTag(v.url(file)))
+	}
+
+	return tags
+}
+
+// url returns the raw public URL of a built file, escaped by the tag builders.
+func (v *Vite) url(file string) string {
+	return strings.TrimSuffix(v.BuildURL, "/") + "/" + file
+}
+
+func devURL(url, path string) string {
+	return strings.TrimSuffix(url, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func isCSS(file string) bool {
+	for _, ext := range []string{".css", ".less", ".sass", ".scss", ".styl", ".stylus", ".pcss", ".postcss"} {
+		if strings.HasSuffix(file, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func styleTag(href string) string {
+	return `<link rel="stylesheet" href="` + template.HTMLEscapeString(href) + `">`
+}
+
+func scriptTag(src string) string {
+	return `<script type="module" src="` + template.HTMLEscapeString(src) + `"></script>`
+}